@@ -49,26 +49,25 @@ func main() {
 		panic(err)
 	}
 
-	// the third argument is the "onmessage" function.  it will be triggered everytime the websocket client receives a message
-	panic(session.MakeWebsocketConnection(func(session *zoom.ZoomSession, message zoom.Message) error {
-		switch m := message.(type) {
-		case *zoom.ConferenceRosterIndication:
-			for _, person := range m.Update {
-				if person.ID != session.JoinInfo.UserID {
-					// Check if not nil -> update to true or false
-					if person.BRaiseHand != nil {
-						raisedHand := *person.BRaiseHand
-						if raisedHand {
-							log.Println(string(person.Dn2) + " has raised their hand!")
-						} else {
-							log.Println(string(person.Dn2) + " has lowered their hand!")
-						}
+	// handlers are registered per message type, you can register as many as you like for the same message
+	_, err = session.On(zoom.WS_CONF_ROSTER_INDICATION, func(m *zoom.ConferenceRosterIndication) {
+		for _, person := range m.Update {
+			if person.ID != session.JoinInfo.UserID {
+				// Check if not nil -> update to true or false
+				if person.BRaiseHand != nil {
+					raisedHand := *person.BRaiseHand
+					if raisedHand {
+						log.Println(string(person.Dn2) + " has raised their hand!")
+					} else {
+						log.Println(string(person.Dn2) + " has lowered their hand!")
 					}
 				}
 			}
-			return nil
-		default:
-			return nil
 		}
-	}))
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	panic(session.MakeWebsocketConnection(nil))
 }
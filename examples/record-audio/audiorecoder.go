@@ -64,14 +64,15 @@ func main() {
 				}
 			}
 			for _, person := range m.Update {
-				if person.AudioConnectionStatus == 2 {
+				if person.AudioConnectionStatus != nil && *person.AudioConnectionStatus == 2 {
 					err = session.MuteUser(person.ID, true)
 				}
 			}
 			return nil
 		case *zoom.JoinConferenceResponse:
 			// TODO(hackish): move this elsewhere
-			streams, err = zoom.CreateZoomAudioStreams(session)
+			// nil records every participant to their own .raw file, pass a zoom.MediaSink to do something else with the audio
+			streams, err = zoom.CreateZoomAudioStreams(session, nil)
 			if err != nil {
 				return err
 			}
@@ -67,15 +67,15 @@ func main() {
 				}
 			}
 			for _, person := range m.Update {
-				if person.ID != session.JoinInfo.UserID {
-					if person.BVideoOn {
+				// BVideoOn is nil when it did not change
+				if person.ID != session.JoinInfo.UserID && person.BVideoOn != nil {
+					if *person.BVideoOn {
 						// Start listening to their video feed
 						// {"evt":12303,"body":{"subInfoList":[{"id":16778240,"size":2,"bOn":false}]},"seq":18}
 						// session.VideoSubscribeRequest(person.ID, 4)
 					} else {
 						// Stop listening to their video feed
 						// {"evt":12305,"body":{"subIDList":[{"id":16778240}]},"seq":17}
-						// TODO: fix is buggy because BVideoOn can be null, and not mean false..
 						// session.VideoUnsubscribeRequest(person.ID)
 					}
 				}
@@ -83,7 +83,7 @@ func main() {
 			return nil
 		case *zoom.JoinConferenceResponse:
 			// TODO(hackish): move this elsewher
-			streams, err = zoom.CreateZoomScreenShareStreams(session)
+			streams, err = zoom.CreateZoomScreenShareStreams(session, nil)
 			if err != nil {
 				return err
 			}
@@ -106,7 +106,7 @@ func main() {
 			return nil
 		case *zoom.SharingAssignedSendingSsrcResponse:
 			// A3. Start broadcasting
-			// streams.StartBroadcast(m.SSRC)
+			// streams.StartScreenShare(ctx, source, frameRate) waits for this, no need to handle it here
 			return nil
 		case *zoom.SharingStatusIndication:
 			// streams.AddSsrcForParticipant(m.ActiveNodeID, m.Ssrc)
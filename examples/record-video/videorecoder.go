@@ -68,24 +68,25 @@ func main() {
 					streams.AddParticipant(person.ID, person.ZoomID)
 				}
 			}
-			for _, person := range m.Update {
-				if person.ID != session.JoinInfo.UserID {
-					if person.BVideoOn {
-						// Start listening to their video feed
-						// {"evt":12303,"body":{"subInfoList":[{"id":16778240,"size":2,"bOn":false}]},"seq":18}
-						session.VideoSubscribeRequest(person.ID, 4)
-					} else {
-						// Stop listening to their video feed
-						// {"evt":12305,"body":{"subIDList":[{"id":16778240}]},"seq":17}
-						// TODO: fix is buggy because BVideoOn can be null, and not mean false..
-						// session.VideoUnsubscribeRequest(person.ID)
-					}
+			return nil
+		case *zoom.VideoChanged:
+			// the session roster tells us when someone actually turned their video on or off
+			person := m.Participant
+			if person.UserID != session.JoinInfo.UserID {
+				if person.VideoOn {
+					// Start listening to their video feed
+					// {"evt":12303,"body":{"subInfoList":[{"id":16778240,"size":2,"bOn":false}]},"seq":18}
+					session.VideoSubscribeRequest(person.UserID, 4)
+				} else {
+					// Stop listening to their video feed
+					// {"evt":12305,"body":{"subIDList":[{"id":16778240}]},"seq":17}
+					session.VideoUnsubscribeRequest(person.UserID)
 				}
 			}
 			return nil
 		case *zoom.JoinConferenceResponse:
 			// TODO(hackish): move this elsewhere
-			streams, err = zoom.CreateZoomVideoStreams(session)
+			streams, err = zoom.CreateZoomVideoStreams(session, nil)
 			if err != nil {
 				return err
 			}
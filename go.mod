@@ -1,12 +1,15 @@
 module github.com/RealKeyboardWarrior/zoomer
 
-go 1.14
+go 1.24.0
 
 require (
 	github.com/google/uuid v1.3.0
 	github.com/gorilla/websocket v1.4.2
 	github.com/joho/godotenv v1.4.0
+	github.com/pion/opus v0.1.0
 	github.com/pion/rtcp v1.2.10
 	github.com/pion/rtp v1.7.13
-	github.com/pion/webrtc/v3 v3.1.49 // indirect
+	github.com/pion/webrtc/v3 v3.1.49
 )
+
+require github.com/pion/randutil v0.1.0 // indirect
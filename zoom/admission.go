@@ -0,0 +1,155 @@
+package zoom
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+type AdmissionDecision int
+
+const (
+	// leave it to the next policy, or leave them waiting if there is none
+	ADMISSION_UNDECIDED AdmissionDecision = iota
+	ADMISSION_ADMIT
+	ADMISSION_HOLD
+)
+
+// AdmissionPolicy decides what happens to someone who shows up in the waiting room
+type AdmissionPolicy interface {
+	Decide(participant Participant) AdmissionDecision
+}
+
+type AdmissionPolicyFunc func(participant Participant) AdmissionDecision
+
+func (policy AdmissionPolicyFunc) Decide(participant Participant) AdmissionDecision {
+	return policy(participant)
+}
+
+// AllowNames admits people whose display name is one of names, ignoring case and surrounding whitespace
+func AllowNames(names ...string) AdmissionPolicy {
+	allowed := make(map[string]bool)
+	for _, name := range names {
+		allowed[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	return AdmissionPolicyFunc(func(participant Participant) AdmissionDecision {
+		if allowed[strings.ToLower(strings.TrimSpace(participant.DisplayName))] {
+			return ADMISSION_ADMIT
+		}
+		return ADMISSION_UNDECIDED
+	})
+}
+
+// AllowNamesMatching admits people whose display name matches the pattern
+func AllowNamesMatching(pattern *regexp.Regexp) AdmissionPolicy {
+	return AdmissionPolicyFunc(func(participant Participant) AdmissionDecision {
+		if pattern.MatchString(participant.DisplayName) {
+			return ADMISSION_ADMIT
+		}
+		return ADMISSION_UNDECIDED
+	})
+}
+
+/*
+AllowEmailDomains admits people signed in with an email address of one of the domains, subdomains included.
+Zoom only tells us the email of people that are signed in, everyone else is left undecided.
+*/
+func AllowEmailDomains(domains ...string) AdmissionPolicy {
+	return AdmissionPolicyFunc(func(participant Participant) AdmissionDecision {
+		at := strings.LastIndex(participant.Email, "@")
+		if at < 0 {
+			return ADMISSION_UNDECIDED
+		}
+		domain := strings.ToLower(participant.Email[at+1:])
+		for _, allowed := range domains {
+			allowed = strings.ToLower(strings.TrimPrefix(allowed, "@"))
+			if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+				return ADMISSION_ADMIT
+			}
+		}
+		return ADMISSION_UNDECIDED
+	})
+}
+
+// Deny keeps whoever the policy would admit in the waiting room, eg. Deny(AllowNames("Zoom User"))
+func Deny(policy AdmissionPolicy) AdmissionPolicy {
+	return AdmissionPolicyFunc(func(participant Participant) AdmissionDecision {
+		if policy.Decide(participant) == ADMISSION_ADMIT {
+			return ADMISSION_HOLD
+		}
+		return ADMISSION_UNDECIDED
+	})
+}
+
+// FirstMatch asks the policies in order, the first one that decides wins
+func FirstMatch(policies ...AdmissionPolicy) AdmissionPolicy {
+	return AdmissionPolicyFunc(func(participant Participant) AdmissionDecision {
+		for _, policy := range policies {
+			decision := policy.Decide(participant)
+			if decision != ADMISSION_UNDECIDED {
+				return decision
+			}
+		}
+		return ADMISSION_UNDECIDED
+	})
+}
+
+/*
+AdmissionController applies a policy to everyone who arrives in the waiting room, the session has to be host
+or co-host for it to do anything.
+
+People the policy admits are admitted right away, everyone else is left waiting for a human to decide. The
+controller only looks at people as they arrive (and at whoever is waiting when it is created), so someone
+sent back to the waiting room by the host stays there.
+*/
+type AdmissionController struct {
+	policy AdmissionPolicy
+	admit  func(userID int) error
+
+	subscription *Subscription
+}
+
+func NewAdmissionController(session *ZoomSession, policy AdmissionPolicy) (*AdmissionController, error) {
+	controller := newAdmissionController(policy, session.AdmitFromWaitingRoom)
+
+	subscription, err := session.On(LOCAL_PARTICIPANT_JOINED, func(joined *ParticipantJoined) {
+		controller.onArrival(joined.Participant)
+	})
+	if err != nil {
+		return nil, err
+	}
+	controller.subscription = subscription
+
+	for _, participant := range session.Roster.WaitingRoom() {
+		controller.onArrival(participant)
+	}
+
+	return controller, nil
+}
+
+func newAdmissionController(policy AdmissionPolicy, admit func(userID int) error) *AdmissionController {
+	return &AdmissionController{
+		policy: policy,
+		admit:  admit,
+	}
+}
+
+// Close stops applying the policy, nobody is sent back to the waiting room
+func (controller *AdmissionController) Close() {
+	if controller.subscription != nil {
+		controller.subscription.Unsubscribe()
+	}
+}
+
+func (controller *AdmissionController) onArrival(participant Participant) {
+	if !participant.IsOnHold {
+		return
+	}
+	if controller.policy.Decide(participant) != ADMISSION_ADMIT {
+		return
+	}
+	err := controller.admit(participant.UserID)
+	if err != nil {
+		log.Printf("Admitting %v (%v) failed: %+v", participant.DisplayName, participant.UserID, err)
+	}
+}
@@ -0,0 +1,50 @@
+package zoom
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestAdmissionPolicies(t *testing.T) {
+	policy := FirstMatch(
+		Deny(AllowNames("Zoom User")),
+		AllowNames(" alice "),
+		AllowNamesMatching(regexp.MustCompile(`^Team - `)),
+		AllowEmailDomains("@example.com"),
+	)
+
+	cases := []struct {
+		participant Participant
+		decision    AdmissionDecision
+	}{
+		{Participant{DisplayName: "Alice"}, ADMISSION_ADMIT},
+		{Participant{DisplayName: "Team - Bob"}, ADMISSION_ADMIT},
+		{Participant{DisplayName: "Carol", Email: "carol@mail.Example.com"}, ADMISSION_ADMIT},
+		{Participant{DisplayName: "Dave", Email: "dave@notexample.com"}, ADMISSION_UNDECIDED},
+		{Participant{DisplayName: "zoom user", Email: "someone@example.com"}, ADMISSION_HOLD},
+		{Participant{DisplayName: "Eve"}, ADMISSION_UNDECIDED},
+	}
+	for _, c := range cases {
+		if decision := policy.Decide(c.participant); decision != c.decision {
+			t.Errorf("expected %v for %+v, got %v", c.decision, c.participant, decision)
+		}
+	}
+}
+
+func TestAdmissionControllerOnlyAdmitsArrivals(t *testing.T) {
+	admitted := make([]int, 0)
+	controller := newAdmissionController(AllowNames("Alice", "Bob"), func(userID int) error {
+		admitted = append(admitted, userID)
+		return nil
+	})
+
+	controller.onArrival(Participant{UserID: 1, DisplayName: "Alice", IsOnHold: true})
+	controller.onArrival(Participant{UserID: 2, DisplayName: "Eve", IsOnHold: true})
+	// Bob joined straight into the meeting, there is nothing to admit
+	controller.onArrival(Participant{UserID: 3, DisplayName: "Bob"})
+
+	if !reflect.DeepEqual(admitted, []int{1}) {
+		t.Errorf("expected only Alice to be admitted, got %v", admitted)
+	}
+}
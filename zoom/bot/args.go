@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var ErrUnterminatedQuote = errors.New("unterminated quote or trailing backslash")
+
+/*
+ParseArgs splits the text after a command into its arguments. Arguments are separated by whitespace,
+double quotes keep an argument with spaces together and a backslash escapes the next character:
+
+	!rename "John Doe" Johnny\ D  =>  ["John Doe", "Johnny D"]
+*/
+func ParseArgs(text string) ([]string, error) {
+	args := make([]string, 0)
+	var current strings.Builder
+	inArg, quoted, escaped := false, false, false
+	for _, r := range text {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			inArg = true
+			escaped = true
+		case r == '"':
+			inArg = true
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			inArg = true
+			current.WriteRune(r)
+		}
+	}
+	if quoted || escaped {
+		return nil, ErrUnterminatedQuote
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := map[string][]string{
+		"":                        {},
+		"  one   two ":            {"one", "two"},
+		`"John Doe" Johnny\ D`:    {"John Doe", "Johnny D"},
+		`say "" nothing`:          {"say", "", "nothing"},
+		`mixed"quo ted"\"escaped`: {`mixedquo ted"escaped`},
+	}
+	for text, expected := range tests {
+		args, err := ParseArgs(text)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("%q: expected %q, got %q", text, expected, args)
+		}
+	}
+
+	for _, text := range []string{`"open`, `trailing\`} {
+		_, err := ParseArgs(text)
+		if err != ErrUnterminatedQuote {
+			t.Errorf("%q: expected ErrUnterminatedQuote, got %v", text, err)
+		}
+	}
+}
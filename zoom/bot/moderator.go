@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom"
+)
+
+// how long an expel may take before we give up on it
+const ENFORCE_TIMEOUT = 5 * time.Second
+
+type ActivityKind int
+
+const (
+	ACTIVITY_CHAT ActivityKind = iota
+	// someone joined or renamed themselves
+	ACTIVITY_NAME
+	// someone started to share their screen
+	ACTIVITY_SHARE
+)
+
+// Activity is something a participant did that the rules of a Moderator are checked against
+type Activity struct {
+	Kind        ActivityKind
+	Participant zoom.Participant
+	// the chat message or the new display name, empty for shares
+	Text string
+}
+
+type Action int
+
+const (
+	// tell everyone in the chat what the participant did wrong
+	ACTION_WARN Action = iota
+	ACTION_MUTE
+	ACTION_STOP_SHARE
+	// send them back to the waiting room
+	ACTION_HOLD
+	ACTION_EXPEL
+)
+
+func (action Action) String() string {
+	switch action {
+	case ACTION_WARN:
+		return "warn"
+	case ACTION_MUTE:
+		return "mute"
+	case ACTION_STOP_SHARE:
+		return "stop share"
+	case ACTION_HOLD:
+		return "hold"
+	case ACTION_EXPEL:
+		return "expel"
+	default:
+		return fmt.Sprintf("action %d", int(action))
+	}
+}
+
+type Matcher func(activity Activity) bool
+
+type Rule struct {
+	Name  string
+	Match Matcher
+	// run in order, a failing action does not stop the others
+	Actions []Action
+	// the warning sent with ACTION_WARN, the name of the rule is used if empty
+	Warning string
+}
+
+// ChatMatching matches chat messages that match the pattern
+func ChatMatching(pattern *regexp.Regexp) Matcher {
+	return func(activity Activity) bool {
+		return activity.Kind == ACTIVITY_CHAT && pattern.MatchString(activity.Text)
+	}
+}
+
+// NameMatching matches people joining with or renaming themselves to a name that matches the pattern
+func NameMatching(pattern *regexp.Regexp) Matcher {
+	return func(activity Activity) bool {
+		return activity.Kind == ACTIVITY_NAME && pattern.MatchString(activity.Text)
+	}
+}
+
+// AnyShare matches every screenshare, for meetings where attendees should not share at all
+func AnyShare() Matcher {
+	return func(activity Activity) bool {
+		return activity.Kind == ACTIVITY_SHARE
+	}
+}
+
+// ChatFlood matches the message that makes someone send more than messages chat messages within window
+func ChatFlood(messages int, window time.Duration) Matcher {
+	var mu sync.Mutex
+	sent := make(map[ /*userId*/ int][]time.Time)
+	return func(activity Activity) bool {
+		if activity.Kind != ACTIVITY_CHAT {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		recent := make([]time.Time, 0, messages+1)
+		for _, at := range sent[activity.Participant.UserID] {
+			if now.Sub(at) < window {
+				recent = append(recent, at)
+			}
+		}
+		recent = append(recent, now)
+		sent[activity.Participant.UserID] = recent
+		return len(recent) > messages
+	}
+}
+
+// what the Moderator needs from the session, *zoom.ZoomSession implements it
+type enforcer interface {
+	SendChatMessage(destNodeID int, text string) error
+	MuteParticipantAudio(userID int) error
+	StopSharing(userID int) error
+	PutOnHold(userID int, hold bool) error
+	ExpelParticipant(ctx context.Context, userID int) error
+}
+
+/*
+Moderator checks what people do in the meeting against a list of rules and enforces the first rule that
+matches. It looks at chat messages, the names people join with or rename themselves to, and screenshares.
+All actions apart from warnings require the session to be host or co-host.
+
+Hosts, co-hosts and the bot itself are never acted on. Rules are checked as soon as zoom tells us about the
+activity, the actions run in their own goroutine so a slow expel does not hold up the other handlers. Actions
+are not retried, while the session is not connected the rule is logged and skipped.
+*/
+type Moderator struct {
+	rules    []Rule
+	enforcer enforcer
+	lookup   func(userID int) (zoom.Participant, bool)
+	isSelf   func(userID int) bool
+
+	mu            sync.Mutex
+	sharer        int
+	subscriptions []*zoom.Subscription
+}
+
+func NewModerator(session *zoom.ZoomSession, rules []Rule) (*Moderator, error) {
+	moderator := newModerator(rules, session, session.Roster.Get, func(userID int) bool {
+		return session.JoinInfo != nil && session.JoinInfo.UserID == userID
+	})
+
+	handlers := map[int]interface{}{
+		zoom.WS_CONF_CHAT_INDICATION: moderator.onChat,
+		zoom.LOCAL_PARTICIPANT_JOINED: func(joined *zoom.ParticipantJoined) {
+			moderator.handle(Activity{Kind: ACTIVITY_NAME, Participant: joined.Participant, Text: joined.Participant.DisplayName})
+		},
+		zoom.LOCAL_PARTICIPANT_RENAMED: func(renamed *zoom.Renamed) {
+			moderator.handle(Activity{Kind: ACTIVITY_NAME, Participant: renamed.Participant, Text: renamed.Participant.DisplayName})
+		},
+		zoom.WS_SHARING_STATUS_INDICATION: moderator.onSharingStatus,
+	}
+	for evt, handler := range handlers {
+		subscription, err := session.On(evt, handler)
+		if err != nil {
+			moderator.Close()
+			return nil, err
+		}
+		moderator.subscriptions = append(moderator.subscriptions, subscription)
+	}
+
+	return moderator, nil
+}
+
+func newModerator(rules []Rule, enforcer enforcer, lookup func(userID int) (zoom.Participant, bool), isSelf func(userID int) bool) *Moderator {
+	return &Moderator{
+		rules:    rules,
+		enforcer: enforcer,
+		lookup:   lookup,
+		isSelf:   isSelf,
+	}
+}
+
+// Close stops checking the rules, actions that are already running finish
+func (moderator *Moderator) Close() {
+	for _, subscription := range moderator.subscriptions {
+		subscription.Unsubscribe()
+	}
+}
+
+func (moderator *Moderator) onChat(indication *zoom.ConferenceChatIndication) {
+	sender, ok := moderator.lookup(indication.AttendeeNodeID)
+	if !ok {
+		sender = zoom.Participant{UserID: indication.AttendeeNodeID, DisplayName: string(indication.SenderName)}
+	}
+	moderator.handle(Activity{Kind: ACTIVITY_CHAT, Participant: sender, Text: string(indication.Text)})
+}
+
+// zoom repeats the status while the same person keeps sharing, only a new sharer counts
+func (moderator *Moderator) onSharingStatus(indication *zoom.SharingStatusIndication) {
+	moderator.mu.Lock()
+	changed := indication.ActiveNodeID != moderator.sharer
+	moderator.sharer = indication.ActiveNodeID
+	moderator.mu.Unlock()
+	if !changed || indication.ActiveNodeID == 0 {
+		return
+	}
+
+	sharer, ok := moderator.lookup(indication.ActiveNodeID)
+	if !ok {
+		sharer = zoom.Participant{UserID: indication.ActiveNodeID}
+	}
+	moderator.handle(Activity{Kind: ACTIVITY_SHARE, Participant: sharer})
+}
+
+func (moderator *Moderator) handle(activity Activity) {
+	rule := moderator.check(activity)
+	if rule != nil {
+		go moderator.enforce(rule, activity.Participant)
+	}
+}
+
+// check returns the first rule the activity breaks, if any. Every rule sees the activity so matchers that
+// count, like ChatFlood, don't miss anything.
+func (moderator *Moderator) check(activity Activity) *Rule {
+	if moderator.isSelf(activity.Participant.UserID) || RoleOf(activity.Participant) >= ROLE_COHOST {
+		return nil
+	}
+	var broken *Rule
+	for i := range moderator.rules {
+		rule := &moderator.rules[i]
+		if rule.Match(activity) && broken == nil {
+			broken = rule
+		}
+	}
+	return broken
+}
+
+func (moderator *Moderator) enforce(rule *Rule, participant zoom.Participant) {
+	for _, action := range rule.Actions {
+		err := moderator.apply(action, rule, participant)
+		if errors.Is(err, zoom.ErrNotConnected) {
+			// reconnecting or left the meeting, the remaining actions would fail the same way
+			log.Printf("Moderation rule %q could not %v %v (%v), the session is not connected", rule.Name, action, participant.DisplayName, participant.UserID)
+			return
+		}
+		if err != nil {
+			log.Printf("Moderation rule %q failed to %v %v (%v): %+v", rule.Name, action, participant.DisplayName, participant.UserID, err)
+		}
+	}
+}
+
+func (moderator *Moderator) apply(action Action, rule *Rule, participant zoom.Participant) error {
+	switch action {
+	case ACTION_WARN:
+		warning := rule.Warning
+		if warning == "" {
+			warning = rule.Name
+		}
+		return moderator.enforcer.SendChatMessage(zoom.EVERYONE_CHAT_ID, fmt.Sprintf("%v: %v", participant.DisplayName, warning))
+	case ACTION_MUTE:
+		return moderator.enforcer.MuteParticipantAudio(participant.UserID)
+	case ACTION_STOP_SHARE:
+		return moderator.enforcer.StopSharing(participant.UserID)
+	case ACTION_HOLD:
+		return moderator.enforcer.PutOnHold(participant.UserID, true)
+	case ACTION_EXPEL:
+		ctx, cancel := context.WithTimeout(context.Background(), ENFORCE_TIMEOUT)
+		defer cancel()
+		return moderator.enforcer.ExpelParticipant(ctx, participant.UserID)
+	default:
+		return fmt.Errorf("unknown action %v", action)
+	}
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom"
+)
+
+type fakeEnforcer struct {
+	calls []string
+	// returned by every call
+	err error
+}
+
+func (enforcer *fakeEnforcer) SendChatMessage(destNodeID int, text string) error {
+	enforcer.calls = append(enforcer.calls, fmt.Sprintf("chat %v %v", destNodeID, text))
+	return enforcer.err
+}
+
+func (enforcer *fakeEnforcer) MuteParticipantAudio(userID int) error {
+	enforcer.calls = append(enforcer.calls, fmt.Sprintf("mute %v", userID))
+	return enforcer.err
+}
+
+func (enforcer *fakeEnforcer) StopSharing(userID int) error {
+	enforcer.calls = append(enforcer.calls, fmt.Sprintf("stop share %v", userID))
+	return enforcer.err
+}
+
+func (enforcer *fakeEnforcer) PutOnHold(userID int, hold bool) error {
+	enforcer.calls = append(enforcer.calls, fmt.Sprintf("hold %v %v", userID, hold))
+	return enforcer.err
+}
+
+func (enforcer *fakeEnforcer) ExpelParticipant(ctx context.Context, userID int) error {
+	enforcer.calls = append(enforcer.calls, fmt.Sprintf("expel %v", userID))
+	return enforcer.err
+}
+
+func TestModeratorRules(t *testing.T) {
+	enforcer := &fakeEnforcer{}
+	participants := map[int]zoom.Participant{
+		testHostID:     {UserID: testHostID, DisplayName: "Host", Role: zoom.USER_ROLE_HOST},
+		testAttendeeID: {UserID: testAttendeeID, DisplayName: "Attendee"},
+	}
+	lookup := func(userID int) (zoom.Participant, bool) {
+		participant, ok := participants[userID]
+		return participant, ok
+	}
+	moderator := newModerator([]Rule{
+		{Name: "no links", Match: ChatMatching(regexp.MustCompile(`https?://`)), Actions: []Action{ACTION_WARN}, Warning: "no links please"},
+		{Name: "slurs", Match: NameMatching(regexp.MustCompile(`(?i)badword`)), Actions: []Action{ACTION_HOLD}},
+		{Name: "no sharing", Match: AnyShare(), Actions: []Action{ACTION_STOP_SHARE, ACTION_WARN}},
+		{Name: "flood", Match: ChatFlood(2, time.Minute), Actions: []Action{ACTION_MUTE, ACTION_EXPEL}},
+	}, enforcer, lookup, func(userID int) bool {
+		return userID == testSelfID
+	})
+
+	attendee := participants[testAttendeeID]
+	if rule := moderator.check(Activity{Kind: ACTIVITY_CHAT, Participant: attendee, Text: "see http://example.com"}); rule == nil || rule.Name != "no links" {
+		t.Errorf("expected the link rule, got %+v", rule)
+	}
+	// the host and the bot itself are left alone
+	if rule := moderator.check(Activity{Kind: ACTIVITY_CHAT, Participant: participants[testHostID], Text: "http://example.com"}); rule != nil {
+		t.Errorf("expected the host to be exempt, got %+v", rule)
+	}
+	if rule := moderator.check(Activity{Kind: ACTIVITY_NAME, Participant: zoom.Participant{UserID: testSelfID}, Text: "BADWORD"}); rule != nil {
+		t.Errorf("expected the bot to be exempt, got %+v", rule)
+	}
+	if rule := moderator.check(Activity{Kind: ACTIVITY_NAME, Participant: attendee, Text: "a BadWord"}); rule == nil || rule.Name != "slurs" {
+		t.Errorf("expected the name rule, got %+v", rule)
+	}
+
+	// the link counted as a message as well, this is the third within a minute
+	if rule := moderator.check(Activity{Kind: ACTIVITY_CHAT, Participant: attendee, Text: "hi"}); rule != nil {
+		t.Errorf("expected no rule for the second message, got %+v", rule)
+	}
+	rule := moderator.check(Activity{Kind: ACTIVITY_CHAT, Participant: attendee, Text: "hi"})
+	if rule == nil || rule.Name != "flood" {
+		t.Errorf("expected the flood rule, got %+v", rule)
+		return
+	}
+	moderator.enforce(rule, attendee)
+
+	moderator.enforce(&moderator.rules[2], attendee)
+	expected := []string{
+		fmt.Sprintf("mute %v", testAttendeeID),
+		fmt.Sprintf("expel %v", testAttendeeID),
+		fmt.Sprintf("stop share %v", testAttendeeID),
+		fmt.Sprintf("chat %v Attendee: no sharing", zoom.EVERYONE_CHAT_ID),
+	}
+	if !reflect.DeepEqual(enforcer.calls, expected) {
+		t.Errorf("expected %v, got %v", expected, enforcer.calls)
+	}
+}
+
+func TestModeratorOnlyCountsNewSharers(t *testing.T) {
+	moderator := newModerator([]Rule{{Name: "no sharing", Match: AnyShare()}}, &fakeEnforcer{}, func(userID int) (zoom.Participant, bool) {
+		return zoom.Participant{}, false
+	}, func(userID int) bool {
+		return false
+	})
+
+	shares := 0
+	moderator.rules[0].Match = func(activity Activity) bool {
+		shares++
+		return false
+	}
+	for _, sharer := range []int{testAttendeeID, testAttendeeID, 0, testAttendeeID} {
+		moderator.onSharingStatus(&zoom.SharingStatusIndication{ActiveNodeID: sharer})
+	}
+	if shares != 2 {
+		t.Errorf("expected 2 shares, got %v", shares)
+	}
+}
+
+func TestModeratorStopsWithoutConnection(t *testing.T) {
+	enforcer := &fakeEnforcer{err: zoom.ErrNotConnected}
+	moderator := newModerator(nil, enforcer, func(userID int) (zoom.Participant, bool) {
+		return zoom.Participant{}, false
+	}, func(userID int) bool {
+		return false
+	})
+
+	rule := &Rule{Name: "flood", Actions: []Action{ACTION_MUTE, ACTION_EXPEL}}
+	moderator.enforce(rule, zoom.Participant{UserID: testAttendeeID, DisplayName: "Attendee"})
+	expected := []string{fmt.Sprintf("mute %v", testAttendeeID)}
+	if fmt.Sprint(enforcer.calls) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, enforcer.calls)
+	}
+}
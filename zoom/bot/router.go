@@ -0,0 +1,309 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom"
+)
+
+const DEFAULT_PREFIX = "!"
+
+// roles are ordered, a command open to co-hosts is open to the host as well
+type Role int
+
+const (
+	ROLE_ATTENDEE Role = iota
+	ROLE_COHOST
+	ROLE_HOST
+)
+
+func (role Role) String() string {
+	switch role {
+	case ROLE_HOST:
+		return "host"
+	case ROLE_COHOST:
+		return "co-host"
+	default:
+		return "attendee"
+	}
+}
+
+func RoleOf(participant zoom.Participant) Role {
+	if participant.IsHost() {
+		return ROLE_HOST
+	}
+	if participant.IsCoHost {
+		return ROLE_COHOST
+	}
+	return ROLE_ATTENDEE
+}
+
+var ErrDuplicateCommand = errors.New("a command with this name already exists")
+
+type HandlerFunc func(invocation *Invocation) error
+
+type Command struct {
+	Name    string
+	Aliases []string
+	// shown in the help after the name, e.g. "<name> [reason]"
+	Usage       string
+	Description string
+	// the least someone has to be to use the command
+	Role Role
+	// the command is not run with fewer arguments, the usage is shown instead
+	MinArgs int
+	Handler HandlerFunc
+}
+
+// Invocation is a command someone sent in the chat
+type Invocation struct {
+	Command *Command
+	Args    []string
+	// the part after the command, unparsed
+	RawArgs string
+	Sender  zoom.Participant
+	// whether the command was sent to us rather than to everyone
+	Private bool
+
+	router *Router
+}
+
+// Reply answers in the chat the command came from, privately to the sender or to everyone
+func (invocation *Invocation) Reply(text string) error {
+	destNodeID := zoom.EVERYONE_CHAT_ID
+	if invocation.Private {
+		destNodeID = invocation.Sender.UserID
+	}
+	return invocation.router.send(destNodeID, text)
+}
+
+func (invocation *Invocation) Replyf(format string, args ...interface{}) error {
+	return invocation.Reply(fmt.Sprintf(format, args...))
+}
+
+/*
+Router runs the commands people send in the meeting chat. Commands start with the prefix ("!" by default),
+followed by the name and the arguments as parsed by ParseArgs. Every router has a help command that lists
+the commands the sender is allowed to use. Messages that only look like commands are ignored, unless
+SetReplyToUnknown asks to point the sender to the help.
+
+Who sent a command is looked up in the roster of the session, people that aren't in it are attendees.
+Commands run in their own goroutine so they can wait for zoom to respond.
+*/
+type Router struct {
+	prefix string
+	send   func(destNodeID int, text string) error
+	lookup func(userID int) (zoom.Participant, bool)
+	isSelf func(userID int) bool
+
+	mu           sync.RWMutex
+	commands     map[ /*name or alias*/ string]*Command
+	subscription *zoom.Subscription
+	// off by default, "!" is used for more than commands in a chat
+	replyToUnknown bool
+}
+
+func NewRouter(session *zoom.ZoomSession, prefix string) (*Router, error) {
+	router := newRouter(prefix, session.SendChatMessage, session.Roster.Get, func(userID int) bool {
+		return session.JoinInfo != nil && session.JoinInfo.UserID == userID
+	})
+
+	subscription, err := session.On(zoom.WS_CONF_CHAT_INDICATION, func(indication *zoom.ConferenceChatIndication) {
+		go router.handle(indication)
+	})
+	if err != nil {
+		return nil, err
+	}
+	router.subscription = subscription
+
+	return router, nil
+}
+
+func newRouter(prefix string, send func(destNodeID int, text string) error, lookup func(userID int) (zoom.Participant, bool), isSelf func(userID int) bool) *Router {
+	if prefix == "" {
+		prefix = DEFAULT_PREFIX
+	}
+	router := &Router{
+		prefix:   prefix,
+		send:     send,
+		lookup:   lookup,
+		isSelf:   isSelf,
+		commands: make(map[string]*Command),
+	}
+	router.Handle(Command{
+		Name:        "help",
+		Usage:       "[command]",
+		Description: "lists the commands you can use",
+		Handler:     router.help,
+	})
+	return router
+}
+
+// Close stops listening to the chat
+func (router *Router) Close() {
+	if router.subscription != nil {
+		router.subscription.Unsubscribe()
+	}
+}
+
+// Handle adds a command, its name and aliases are case insensitive
+func (router *Router) Handle(command Command) error {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	names := append([]string{command.Name}, command.Aliases...)
+	for _, name := range names {
+		if _, exists := router.commands[strings.ToLower(name)]; exists {
+			return ErrDuplicateCommand
+		}
+	}
+	for _, name := range names {
+		router.commands[strings.ToLower(name)] = &command
+	}
+	return nil
+}
+
+// SetReplyToUnknown makes the router answer commands it doesn't know with a pointer to the help
+func (router *Router) SetReplyToUnknown(reply bool) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	router.replyToUnknown = reply
+}
+
+func (router *Router) get(name string) *Command {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+	return router.commands[strings.ToLower(name)]
+}
+
+// Commands returns every command once, ordered by name
+func (router *Router) Commands() []*Command {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+
+	commands := make([]*Command, 0, len(router.commands))
+	for name, command := range router.commands {
+		if name == strings.ToLower(command.Name) {
+			commands = append(commands, command)
+		}
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+func (router *Router) handle(indication *zoom.ConferenceChatIndication) {
+	text := strings.TrimSpace(string(indication.Text))
+	if !strings.HasPrefix(text, router.prefix) || router.isSelf(indication.AttendeeNodeID) {
+		return
+	}
+	text = strings.TrimPrefix(text, router.prefix)
+	name, rawArgs := text, ""
+	if index := strings.IndexFunc(text, unicode.IsSpace); index >= 0 {
+		name, rawArgs = text[:index], strings.TrimSpace(text[index:])
+	}
+	if name == "" {
+		return
+	}
+
+	sender, ok := router.lookup(indication.AttendeeNodeID)
+	if !ok {
+		sender = zoom.Participant{UserID: indication.AttendeeNodeID, DisplayName: string(indication.SenderName)}
+	}
+	invocation := &Invocation{
+		RawArgs: rawArgs,
+		Sender:  sender,
+		Private: indication.DestNodeID != zoom.EVERYONE_CHAT_ID,
+		router:  router,
+	}
+
+	err := router.run(invocation, name)
+	if err != nil {
+		log.Printf("Command %v%v of %v failed: %+v", router.prefix, name, sender.UserID, err)
+	}
+}
+
+func (router *Router) run(invocation *Invocation, name string) error {
+	command := router.get(name)
+	if command == nil {
+		router.mu.RLock()
+		reply := router.replyToUnknown
+		router.mu.RUnlock()
+		if !reply {
+			return nil
+		}
+		return invocation.Replyf("Unknown command %v%v, try %vhelp", router.prefix, name, router.prefix)
+	}
+	invocation.Command = command
+
+	if RoleOf(invocation.Sender) < command.Role {
+		return invocation.Replyf("You need to be %v to use %v%v", command.Role, router.prefix, command.Name)
+	}
+	args, err := ParseArgs(invocation.RawArgs)
+	if err != nil {
+		return invocation.Replyf("%v, usage: %v", err, router.usage(command))
+	}
+	if len(args) < command.MinArgs {
+		return invocation.Replyf("Usage: %v", router.usage(command))
+	}
+	invocation.Args = args
+
+	err = command.Handler(invocation)
+	if err != nil {
+		replyErr := invocation.Replyf("%v%v failed: %v", router.prefix, command.Name, err)
+		if replyErr != nil {
+			log.Printf("Replying to %v failed: %+v", invocation.Sender.UserID, replyErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (router *Router) usage(command *Command) string {
+	usage := router.prefix + command.Name
+	if command.Usage != "" {
+		usage += " " + command.Usage
+	}
+	return usage
+}
+
+// help lists the commands the sender may use, or explains a single one
+func (router *Router) help(invocation *Invocation) error {
+	if len(invocation.Args) > 0 {
+		command := router.get(strings.TrimPrefix(invocation.Args[0], router.prefix))
+		if command == nil {
+			return invocation.Replyf("Unknown command %v", invocation.Args[0])
+		}
+		help := router.usage(command)
+		if command.Description != "" {
+			help += " - " + command.Description
+		}
+		if len(command.Aliases) > 0 {
+			help += " (also " + router.prefix + strings.Join(command.Aliases, ", "+router.prefix) + ")"
+		}
+		if command.Role > ROLE_ATTENDEE {
+			help += " [" + command.Role.String() + "]"
+		}
+		return invocation.Reply(help)
+	}
+
+	lines := []string{"Commands:"}
+	for _, command := range router.Commands() {
+		if RoleOf(invocation.Sender) < command.Role {
+			continue
+		}
+		line := router.usage(command)
+		if command.Description != "" {
+			line += " - " + command.Description
+		}
+		lines = append(lines, line)
+	}
+	return invocation.Reply(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom"
+)
+
+type sentMessage struct {
+	destNodeID int
+	text       string
+}
+
+const (
+	testSelfID     = 16778240
+	testHostID     = 16778241
+	testAttendeeID = 16778242
+)
+
+func newTestRouter(sent *[]sentMessage) *Router {
+	participants := map[int]zoom.Participant{
+		testHostID:     {UserID: testHostID, DisplayName: "Host", Role: zoom.USER_ROLE_HOST},
+		testAttendeeID: {UserID: testAttendeeID, DisplayName: "Attendee", Role: zoom.USER_ROLE_ATTENDEE},
+	}
+	send := func(destNodeID int, text string) error {
+		*sent = append(*sent, sentMessage{destNodeID, text})
+		return nil
+	}
+	lookup := func(userID int) (zoom.Participant, bool) {
+		participant, ok := participants[userID]
+		return participant, ok
+	}
+	isSelf := func(userID int) bool {
+		return userID == testSelfID
+	}
+	return newRouter("", send, lookup, isSelf)
+}
+
+func chat(from int, to int, text string) *zoom.ConferenceChatIndication {
+	return &zoom.ConferenceChatIndication{
+		AttendeeNodeID: from,
+		DestNodeID:     to,
+		Text:           []byte(text),
+	}
+}
+
+func TestRouterRunsCommands(t *testing.T) {
+	var sent []sentMessage
+	router := newTestRouter(&sent)
+
+	var got *Invocation
+	err := router.Handle(Command{
+		Name:    "rename",
+		Aliases: []string{"rn"},
+		Usage:   "<name>",
+		MinArgs: 1,
+		Handler: func(invocation *Invocation) error {
+			got = invocation
+			return invocation.Reply("renamed to " + invocation.Args[0])
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if router.Handle(Command{Name: "rn"}) != ErrDuplicateCommand {
+		t.Error("expected aliases to be taken")
+	}
+
+	// in public, the reply goes to everyone
+	router.handle(chat(testAttendeeID, zoom.EVERYONE_CHAT_ID, `  !rename "John Doe" please`))
+	if got == nil || !reflect.DeepEqual(got.Args, []string{"John Doe", "please"}) || got.RawArgs != `"John Doe" please` || got.Sender.UserID != testAttendeeID {
+		t.Errorf("unexpected invocation %+v", got)
+	}
+	// privately, the reply goes to the sender
+	got = nil
+	router.handle(chat(testAttendeeID, testSelfID, "!RN Johnny"))
+	if got == nil || !got.Private {
+		t.Errorf("expected a private invocation, got %+v", got)
+	}
+
+	expected := []sentMessage{
+		{zoom.EVERYONE_CHAT_ID, "renamed to John Doe"},
+		{testAttendeeID, "renamed to Johnny"},
+	}
+	if !reflect.DeepEqual(sent, expected) {
+		t.Errorf("expected %v, got %v", expected, sent)
+	}
+
+	// not enough arguments, plain chat and our own messages
+	sent = nil
+	router.handle(chat(testAttendeeID, zoom.EVERYONE_CHAT_ID, "!rename"))
+	router.handle(chat(testAttendeeID, zoom.EVERYONE_CHAT_ID, "hello !rename"))
+	router.handle(chat(testSelfID, zoom.EVERYONE_CHAT_ID, "!rename me"))
+	expected = []sentMessage{{zoom.EVERYONE_CHAT_ID, "Usage: !rename <name>"}}
+	if !reflect.DeepEqual(sent, expected) {
+		t.Errorf("expected %v, got %v", expected, sent)
+	}
+
+	// unknown commands are ignored unless asked otherwise
+	sent = nil
+	router.handle(chat(testAttendeeID, zoom.EVERYONE_CHAT_ID, "!!!"))
+	router.SetReplyToUnknown(true)
+	router.handle(chat(testAttendeeID, testSelfID, "!unknown"))
+	expected = []sentMessage{{testAttendeeID, "Unknown command !unknown, try !help"}}
+	if !reflect.DeepEqual(sent, expected) {
+		t.Errorf("expected %v, got %v", expected, sent)
+	}
+}
+
+func TestRouterChecksRoles(t *testing.T) {
+	var sent []sentMessage
+	router := newTestRouter(&sent)
+
+	ran := 0
+	router.Handle(Command{
+		Name:        "kick",
+		Description: "removes someone from the meeting",
+		Role:        ROLE_COHOST,
+		Handler: func(invocation *Invocation) error {
+			ran++
+			return errors.New("not today")
+		},
+	})
+
+	router.handle(chat(testAttendeeID, zoom.EVERYONE_CHAT_ID, "!kick"))
+	router.handle(chat(testHostID, zoom.EVERYONE_CHAT_ID, "!kick"))
+	if ran != 1 {
+		t.Errorf("expected only the host to be allowed, ran %v times", ran)
+	}
+	expected := []sentMessage{
+		{zoom.EVERYONE_CHAT_ID, "You need to be co-host to use !kick"},
+		{zoom.EVERYONE_CHAT_ID, "!kick failed: not today"},
+	}
+	if !reflect.DeepEqual(sent, expected) {
+		t.Errorf("expected %v, got %v", expected, sent)
+	}
+
+	// help only lists what the sender can use
+	sent = nil
+	router.handle(chat(testAttendeeID, testSelfID, "!help"))
+	router.handle(chat(testHostID, testSelfID, "!help"))
+	router.handle(chat(testHostID, testSelfID, "!help !kick"))
+	if len(sent) != 3 {
+		t.Errorf("expected 3 replies, got %v", sent)
+		return
+	}
+	if strings.Contains(sent[0].text, "kick") || !strings.Contains(sent[0].text, "!help [command]") {
+		t.Errorf("unexpected help for attendees %q", sent[0].text)
+	}
+	if !strings.Contains(sent[1].text, "!kick - removes someone from the meeting") {
+		t.Errorf("unexpected help for the host %q", sent[1].text)
+	}
+	if sent[2].text != "!kick - removes someone from the meeting [co-host]" {
+		t.Errorf("unexpected help for kick %q", sent[2].text)
+	}
+}
@@ -0,0 +1,161 @@
+package zoom
+
+import (
+	"context"
+	"log"
+)
+
+/*
+JoinBreakoutRoom moves the session into the breakout room with the given bID and returns once we are in it.
+
+Breakout rooms are meetings within the meeting, joining one means reconnecting the signaling websocket with the
+token zoom hands out for the room as "opt". The roster is replaced by the people in the room (you get a
+LOCAL_PARTICIPANT_JOINED for each of them) and the media streams created on the session are re-dialed.
+
+This waits for responses from zoom, so don't call it synchronously from a handler.
+*/
+func (session *ZoomSession) JoinBreakoutRoom(ctx context.Context, bid string) error {
+	joinResponse, err := session.RequestBreakoutRoomJoinToken(ctx, bid)
+	if err != nil {
+		return err
+	}
+
+	session.mu.Lock()
+	previousRoom := session.breakoutRoom
+	previousMainMeetingOpt := session.mainMeetingOpt
+	// moving between breakout rooms keeps the original main meeting
+	if session.breakoutRoom == "" {
+		session.mainMeetingOpt = session.meetingOpt
+	}
+	session.breakoutRoom = joinResponse.Bid
+	session.mu.Unlock()
+
+	err = session.switchMeeting(ctx, joinResponse.Botoken)
+	if err != nil {
+		session.mu.Lock()
+		session.breakoutRoom = previousRoom
+		session.mainMeetingOpt = previousMainMeetingOpt
+		session.mu.Unlock()
+		return err
+	}
+	return session.reconnectStreams()
+}
+
+/*
+ReturnToMainRoom leaves the breakout room and moves the session back into the main meeting.
+
+You don't have to call this when the host closes the breakout rooms, the session goes back on its own.
+*/
+func (session *ZoomSession) ReturnToMainRoom(ctx context.Context) error {
+	if session.BreakoutRoom() == "" {
+		return ErrNotInBreakoutRoom
+	}
+
+	// only forget about the room once zoom got the request, so a failed send can be retried
+	err := session.SendMessage(WS_CONF_BO_LEAVE_REQ, ConferenceBreakoutRoomLeaveRequest{})
+	if err != nil {
+		return err
+	}
+	mainMeetingOpt, ok := session.leaveBreakoutRoom()
+	if !ok {
+		// the host closed the rooms in the meantime, we are on our way back already
+		return nil
+	}
+
+	err = session.switchMeeting(ctx, mainMeetingOpt)
+	if err != nil {
+		return err
+	}
+	return session.reconnectStreams()
+}
+
+// BreakoutRoom returns the bID of the breakout room we are in, or an empty string when we are in the main meeting
+func (session *ZoomSession) BreakoutRoom() string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.breakoutRoom
+}
+
+// leaveBreakoutRoom forgets about the breakout room and returns the opt of the main meeting
+func (session *ZoomSession) leaveBreakoutRoom() (string, bool) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.breakoutRoom == "" {
+		return "", false
+	}
+	mainMeetingOpt := session.mainMeetingOpt
+	session.breakoutRoom = ""
+	session.mainMeetingOpt = ""
+	return mainMeetingOpt, true
+}
+
+// switchMeeting hands the switch to the goroutine serving the websocket and waits until we are in the other meeting
+func (session *ZoomSession) switchMeeting(ctx context.Context, opt string) error {
+	next := &meetingSwitch{
+		opt:    opt,
+		joined: make(chan struct{}),
+	}
+
+	select {
+	case session.switches <- next:
+	case <-session.done:
+		return session.endedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-next.joined:
+		return nil
+	case <-session.done:
+		return session.endedErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reconnectStreamsWhenJoined is used when zoom moves us back to the main meeting by itself
+func (session *ZoomSession) reconnectStreamsWhenJoined(joined chan struct{}) {
+	select {
+	case <-joined:
+	case <-session.done:
+		return
+	}
+	err := session.reconnectStreams()
+	if err != nil {
+		log.Printf("Reconnecting streams failed: %+v", err)
+	}
+}
+
+func (session *ZoomSession) addStreams(streams *ZoomStreams) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.streams = append(session.streams, streams)
+}
+
+// reconnectStreams moves all media streams over to the meeting we are currently in
+func (session *ZoomSession) reconnectStreams() error {
+	session.mu.Lock()
+	allStreams := make([]*ZoomStreams, len(session.streams))
+	copy(allStreams, session.streams)
+	session.mu.Unlock()
+
+	var firstErr error
+	for _, streams := range allStreams {
+		err := streams.switchMeeting()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// endedErr is what we report when the session ends while we are moving to another meeting
+func (session *ZoomSession) endedErr() error {
+	err := session.Err()
+	if err == nil {
+		return ErrNotConnected
+	}
+	return err
+}
@@ -0,0 +1,178 @@
+package zoom
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// BreakoutHelpRequested is emitted when someone in a breakout room asks the host for help
+type BreakoutHelpRequested struct {
+	RequestID int
+	BID       string
+}
+
+type BreakoutRoomOptions struct {
+	// move people into their rooms without asking them first
+	AutoJoin bool
+	// close the rooms after this long, zero keeps them open until StopAll
+	Duration time.Duration
+	// how long people get to return to the main meeting once the rooms are stopped
+	ReturnDelay time.Duration
+}
+
+/*
+BreakoutManager runs breakout rooms from the host side, all of it requires the session to be host or co-host.
+
+The manager keeps track of the rooms through the WS_CONF_BO_ATTRIBUTE_INDICATION messages and reports help
+requests from the rooms as LOCAL_BREAKOUT_HELP_REQUESTED events on the session.
+*/
+type BreakoutManager struct {
+	session *ZoomSession
+
+	mu            sync.Mutex
+	attributes    ConferenceBreakoutRoomAttributeIndicationData
+	subscriptions []*Subscription
+}
+
+func NewBreakoutManager(session *ZoomSession) (*BreakoutManager, error) {
+	manager := &BreakoutManager{
+		session: session,
+	}
+
+	attributeSubscription, err := session.On(WS_CONF_BO_ATTRIBUTE_INDICATION, manager.onAttributes)
+	if err != nil {
+		return nil, err
+	}
+	commandSubscription, err := session.On(WS_CONF_BO_COMMAND_INDICATION, manager.onCommand)
+	if err != nil {
+		attributeSubscription.Unsubscribe()
+		return nil, err
+	}
+	manager.subscriptions = []*Subscription{attributeSubscription, commandSubscription}
+
+	return manager, nil
+}
+
+// Close stops tracking the rooms, it does not stop the rooms themselves
+func (manager *BreakoutManager) Close() {
+	for _, subscription := range manager.subscriptions {
+		subscription.Unsubscribe()
+	}
+}
+
+func (manager *BreakoutManager) onAttributes(indication *ConferenceBreakoutRoomAttributeIndication) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.attributes = ConferenceBreakoutRoomAttributeIndicationData(indication.Proto)
+}
+
+func (manager *BreakoutManager) onCommand(indication *ConferenceBreakoutRoomCommandIndication) {
+	if indication.CommandType != BO_COMMAND_TYPE_HELP {
+		return
+	}
+	manager.session.emit(LOCAL_BREAKOUT_HELP_REQUESTED, &BreakoutHelpRequested{
+		RequestID: indication.RequestID,
+		BID:       indication.TargetBID,
+	})
+}
+
+// Rooms returns the rooms as last reported by zoom
+func (manager *BreakoutManager) Rooms() []BreakoutRoomItem {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	rooms := make([]BreakoutRoomItem, len(manager.attributes.ItemList))
+	copy(rooms, manager.attributes.ItemList)
+	return rooms
+}
+
+func (manager *BreakoutManager) Started() bool {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	return manager.attributes.ControlStatus == BO_CONTROL_STATUS_STARTED
+}
+
+func (manager *BreakoutManager) hasRoom(bid string) bool {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	for _, room := range manager.attributes.ItemList {
+		if room.BID == bid {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+CreateRooms creates and starts a breakout room for every name, the rooms are returned in the same order.
+
+Zoom hands out the bID of every room separately, so this waits for one WS_CONF_BO_TOKEN_RES per room before
+starting them. Don't call it synchronously from a handler.
+*/
+func (manager *BreakoutManager) CreateRooms(ctx context.Context, names []string, options BreakoutRoomOptions) ([]BreakoutRoomItem, error) {
+	if len(names) == 0 {
+		return nil, errors.New("no breakout rooms to create")
+	}
+
+	rooms := make([]BreakoutRoomItem, 0, len(names))
+	for i, name := range names {
+		// zoom numbers the rooms starting at 1
+		bid, err := manager.session.RequestBreakoutRoomToken(ctx, name, i+1)
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, BreakoutRoomItem{
+			BID:             bid,
+			MeetingTitle:    name,
+			ParticipantList: []string{},
+		})
+	}
+
+	err := manager.session.CreateBreakoutRoom(rooms, options.AutoJoin, options.Duration > 0, int(options.Duration.Seconds()), int(options.ReturnDelay.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+
+	// don't wait for the attribute indication, people can be assigned right away
+	manager.mu.Lock()
+	manager.attributes.ControlStatus = BO_CONTROL_STATUS_STARTED
+	manager.attributes.ItemList = rooms
+	manager.mu.Unlock()
+
+	return rooms, nil
+}
+
+/*
+Assign puts someone from the main meeting into a room. Before the rooms are started they are pre-assigned by
+their zoom ID, which means they have to be in the roster.
+*/
+func (manager *BreakoutManager) Assign(ctx context.Context, userID int, bid string) error {
+	if !manager.hasRoom(bid) {
+		return ErrUnknownBreakoutRoom
+	}
+	if manager.Started() {
+		return manager.session.AssignBreakoutRoom(userID, bid)
+	}
+
+	participant, ok := manager.session.Roster.Get(userID)
+	if !ok {
+		return errors.New("can only pre-assign people who are in the roster")
+	}
+	return manager.session.PreAssignBreakoutRoom(ctx, bid, []string{participant.ZoomID})
+}
+
+// Move takes someone who is in a room to another room
+func (manager *BreakoutManager) Move(userID int, bid string) error {
+	if !manager.hasRoom(bid) {
+		return ErrUnknownBreakoutRoom
+	}
+	return manager.session.SwitchBreakoutRoom(userID, bid)
+}
+
+// StopAll closes all rooms, everyone gets ReturnDelay to go back to the main meeting
+func (manager *BreakoutManager) StopAll() error {
+	return manager.session.StopBreakoutRooms()
+}
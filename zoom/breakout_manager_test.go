@@ -0,0 +1,129 @@
+package zoom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBreakoutManagerCreateRooms(t *testing.T) {
+	started := make(chan ConferenceBreakoutRoomStartRequest, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		for {
+			request := &GenericZoomMessage{}
+			err := connection.ReadJSON(request)
+			if err != nil {
+				return
+			}
+			switch request.Evt {
+			case WS_CONF_BO_TOKEN_BATCH_REQ:
+				body := ConferenceBreakoutRoomTokenBatchRequest{}
+				json.Unmarshal(request.Body, &body)
+				connection.WriteJSON(&GenericZoomMessage{
+					Evt:  WS_CONF_BO_TOKEN_RES,
+					Body: []byte(fmt.Sprintf(`{"bid":"bid-%d"}`, body.Index)),
+				})
+			case WS_CONF_BO_START_REQ:
+				body := ConferenceBreakoutRoomStartRequest{}
+				err := json.Unmarshal(request.Body, &body)
+				if err == nil {
+					started <- body
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	connection, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer connection.Close()
+
+	session := &ZoomSession{
+		events:              newEventBus(),
+		requests:            newPendingRequests(),
+		websocketConnection: connection,
+	}
+	go session.readLoop(connection, func() {})
+
+	manager, err := NewBreakoutManager(session)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer manager.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rooms, err := manager.CreateRooms(ctx, []string{"red", "blue"}, BreakoutRoomOptions{Duration: time.Minute})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(rooms) != 2 || rooms[0].BID != "bid-1" || rooms[1].BID != "bid-2" {
+		t.Errorf("unexpected rooms %+v", rooms)
+		return
+	}
+
+	select {
+	case request := <-started:
+		if len(request.Proto.ItemList) != 2 || request.Proto.ItemList[1].MeetingTitle != "blue" {
+			t.Errorf("unexpected rooms in start request %+v", request.Proto.ItemList)
+		}
+		if !request.Proto.IsTimerEnabled || request.Proto.TimerDuration != 60 {
+			t.Errorf("expected a 60 second timer, got %+v", request.Proto)
+		}
+	case <-ctx.Done():
+		t.Error("rooms were never started")
+		return
+	}
+
+	if err := manager.Move(1, "bid-3"); err != ErrUnknownBreakoutRoom {
+		t.Errorf("expected ErrUnknownBreakoutRoom, got %v", err)
+	}
+}
+
+func TestBreakoutManagerHelpRequested(t *testing.T) {
+	session := &ZoomSession{
+		events: newEventBus(),
+	}
+	manager, err := NewBreakoutManager(session)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer manager.Close()
+
+	var requested *BreakoutHelpRequested
+	_, err = session.On(LOCAL_BREAKOUT_HELP_REQUESTED, func(m *BreakoutHelpRequested) {
+		requested = m
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	session.events.dispatch(session, WS_CONF_BO_COMMAND_INDICATION, &ConferenceBreakoutRoomCommandIndication{
+		CommandType: BO_COMMAND_TYPE_HELP,
+		RequestID:   7,
+		TargetBID:   "bid-1",
+	})
+	if requested == nil || requested.RequestID != 7 || requested.BID != "bid-1" {
+		t.Errorf("expected a help request from bid-1, got %+v", requested)
+	}
+}
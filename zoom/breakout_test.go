@@ -0,0 +1,80 @@
+package zoom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func readLoopUntilEnd(t *testing.T, session *ZoomSession) (*meetingSwitch, error) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		connection.WriteJSON(&GenericZoomMessage{Evt: WS_CONF_END_INDICATION, Body: []byte(`{"reason":0}`)})
+		connection.ReadMessage()
+	}))
+	defer server.Close()
+
+	connection, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer connection.Close()
+
+	return session.readLoop(connection, func() {})
+}
+
+func TestBreakoutRoomEnded(t *testing.T) {
+	session := &ZoomSession{
+		events:         newEventBus(),
+		requests:       newPendingRequests(),
+		done:           make(chan struct{}),
+		breakoutRoom:   "bid",
+		mainMeetingOpt: "main",
+	}
+	defer close(session.done)
+
+	next, err := readLoopUntilEnd(t, session)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if next == nil || next.opt != "main" {
+		t.Errorf("expected a switch back to the main meeting, got %+v", next)
+		return
+	}
+	if session.BreakoutRoom() != "" {
+		t.Errorf("expected to be back in the main meeting, still in %v", session.BreakoutRoom())
+	}
+
+	// the main meeting ending ends the session
+	_, err = readLoopUntilEnd(t, session)
+	if err != ErrMeetingEnded {
+		t.Errorf("expected ErrMeetingEnded, got %v", err)
+	}
+}
+
+func TestReturnToMainRoomKeepsRoomWhenSendFails(t *testing.T) {
+	// not connected, eg. while reconnecting
+	session := &ZoomSession{
+		events:         newEventBus(),
+		requests:       newPendingRequests(),
+		breakoutRoom:   "bid",
+		mainMeetingOpt: "main",
+	}
+	err := session.ReturnToMainRoom(context.Background())
+	if err != ErrNotConnected {
+		t.Errorf("expected ErrNotConnected, got %v", err)
+	}
+	if session.BreakoutRoom() != "bid" || session.mainMeetingOpt != "main" {
+		t.Error("expected to still know about the breakout room so we can retry")
+	}
+}
@@ -0,0 +1,95 @@
+package zoom
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// the language captions are tagged with when none is given
+const DEFAULT_CAPTION_LANGUAGE = "en-US"
+
+/*
+CaptionPublisher sends closed captions that show up in the caption area of everyone's zoom client, for
+example the output of a speech to text engine. Zoom only takes captions from the host and from whoever the
+host assigned to type them.
+
+Captions are sent one at a time and every Publish waits for zoom to confirm, so they arrive in order and
+carry increasing sequence numbers. Like Request, Publish blocks when called from a handler.
+*/
+type CaptionPublisher struct {
+	session *ZoomSession
+	// whether we assigned ourselves and should take it back in Close
+	assigned bool
+
+	mu       sync.Mutex
+	language string
+	seq      int
+}
+
+/*
+NewCaptionPublisher starts publishing captions tagged with the language, DEFAULT_CAPTION_LANGUAGE if empty.
+If the session is host but not a captioner yet, it assigns itself first.
+*/
+func NewCaptionPublisher(session *ZoomSession, language string) (*CaptionPublisher, error) {
+	publisher := &CaptionPublisher{
+		session: session,
+	}
+	publisher.SetLanguage(language)
+
+	self, ok := session.self()
+	if ok && self.IsHost() && !self.IsCaptioner {
+		err := session.AssignCaptioner(self.UserID, true)
+		if err != nil {
+			return nil, err
+		}
+		publisher.assigned = true
+	}
+
+	return publisher, nil
+}
+
+// SetLanguage changes the language tag of the captions that follow
+func (publisher *CaptionPublisher) SetLanguage(language string) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if language == "" {
+		language = DEFAULT_CAPTION_LANGUAGE
+	}
+	publisher.language = language
+}
+
+// Publish sends a line of captions and waits until zoom confirms it, a *ResponseError means zoom refused
+func (publisher *CaptionPublisher) Publish(ctx context.Context, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	session := publisher.session
+	self, ok := session.self()
+	if ok && !self.IsHost() && !self.IsCaptioner {
+		return &PermissionError{Evt: WS_CONF_CLOSED_CAPTION_REQ, Required: "captioner"}
+	}
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	publisher.seq++
+	sendBody := ConferenceClosedCaptionRequest{
+		Text: text,
+		Lang: publisher.language,
+		Seq:  publisher.seq,
+	}
+	_, err := session.Request(ctx, WS_CONF_CLOSED_CAPTION_REQ, sendBody)
+	return err
+}
+
+// Close gives up the captioner role if NewCaptionPublisher assigned it
+func (publisher *CaptionPublisher) Close() error {
+	if !publisher.assigned {
+		return nil
+	}
+	publisher.assigned = false
+	return publisher.session.AssignCaptioner(publisher.session.JoinInfo.UserID, false)
+}
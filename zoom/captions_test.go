@@ -0,0 +1,96 @@
+package zoom
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCaptionPublisher(t *testing.T) {
+	received := make(chan ConferenceClosedCaptionRequest, 3)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		for {
+			request := &GenericZoomMessage{}
+			err := connection.ReadJSON(request)
+			if err != nil {
+				return
+			}
+			if request.Evt == WS_CONF_CLOSED_CAPTION_REQ {
+				body := ConferenceClosedCaptionRequest{}
+				json.Unmarshal(request.Body, &body)
+				received <- body
+				connection.WriteJSON(&GenericZoomMessage{
+					Evt:  WS_CONF_CLOSED_CAPTION_RES,
+					Body: []byte(`{"res":0}`),
+				})
+			}
+		}
+	}))
+	defer server.Close()
+
+	connection, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer connection.Close()
+
+	session := &ZoomSession{
+		JoinInfo:            &JoinConferenceResponse{UserID: 16778240},
+		Roster:              NewRoster(),
+		events:              newEventBus(),
+		requests:            newPendingRequests(),
+		websocketConnection: connection,
+	}
+	go session.readLoop(connection, func() {})
+	applyRosterJSON(t, session.Roster, `{"add":[{"id":16778240,"dn2":"Qm90"}],"remove":null,"update":null}`)
+
+	publisher, err := NewCaptionPublisher(session, "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer publisher.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = publisher.Publish(ctx, "hello")
+	if _, ok := err.(*PermissionError); !ok {
+		t.Errorf("expected a PermissionError before we are a captioner, got %v", err)
+	}
+
+	applyRosterJSON(t, session.Roster, `{"add":null,"remove":null,"update":[{"id":16778240,"bCCEditor":true}]}`)
+	for _, text := range []string{"hello", "  ", "bonjour"} {
+		if text == "bonjour" {
+			publisher.SetLanguage("fr-FR")
+		}
+		err = publisher.Publish(ctx, text)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	expected := []ConferenceClosedCaptionRequest{
+		{Text: "hello", Lang: DEFAULT_CAPTION_LANGUAGE, Seq: 1},
+		{Text: "bonjour", Lang: "fr-FR", Seq: 2},
+	}
+	for _, caption := range expected {
+		if got := <-received; !reflect.DeepEqual(got, caption) {
+			t.Errorf("expected %+v, got %+v", caption, got)
+		}
+	}
+}
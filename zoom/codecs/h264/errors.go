@@ -7,4 +7,5 @@ var (
 	ErrInvalidLength           = errors.New("invalid length")
 	ErrNonEmptyBufferStartBit  = errors.New("buffer was not empty while start bit was received")
 	ErrEmptyBufferContinuation = errors.New("buffer was empty while continuation was received")
+	ErrInvalidFraming          = errors.New("frame is neither annex B nor length prefixed")
 )
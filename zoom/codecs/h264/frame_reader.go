@@ -0,0 +1,79 @@
+package h264
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/pion/webrtc/v3/pkg/media/h264reader"
+)
+
+var annexBStartCode = []byte{0, 0, 0, 1}
+
+// VideoSource hands out annex B access units (one picture each) to publish, it returns io.EOF once there is nothing left
+type VideoSource interface {
+	ReadFrame() ([]byte, error)
+}
+
+// KeyFrameRequester is implemented by sources that can produce an IDR on demand, like a live encoder
+type KeyFrameRequester interface {
+	RequestKeyFrame()
+}
+
+/*
+AnnexBReader is a VideoSource for raw H.264 elementary streams, e.g. made with
+ffmpeg -i in.mp4 -c:v libx264 -profile:v baseline -bf 0 -an -f h264 out.h264
+
+It groups the NAL units into access units using the rules of ITU-T H.264 section 7.4.1.2.3, SEI is dropped.
+*/
+type AnnexBReader struct {
+	reader *h264reader.H264Reader
+	// the first NAL unit of the next access unit
+	next *h264reader.NAL
+}
+
+func NewAnnexBReader(reader io.Reader) (*AnnexBReader, error) {
+	h264Reader, err := h264reader.NewReader(bufio.NewReader(reader))
+	if err != nil {
+		return nil, err
+	}
+	return &AnnexBReader{
+		reader: h264Reader,
+	}, nil
+}
+
+func (reader *AnnexBReader) ReadFrame() ([]byte, error) {
+	var frame []byte
+	hasPicture := false
+	for {
+		nal := reader.next
+		reader.next = nil
+		if nal == nil {
+			var err error
+			nal, err = reader.reader.NextNAL()
+			if err == io.EOF && len(frame) > 0 {
+				return frame, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		switch {
+		case nal.UnitType >= h264reader.NalUnitTypeCodedSliceNonIdr && nal.UnitType <= h264reader.NalUnitTypeCodedSliceIdr:
+			// first_mb_in_slice is 0 for the first slice of a picture, a single 1 bit in exp-golomb
+			if hasPicture && len(nal.Data) > 1 && nal.Data[1]&0x80 != 0 {
+				reader.next = nal
+				return frame, nil
+			}
+			hasPicture = true
+		case nal.UnitType >= h264reader.NalUnitTypeSEI && nal.UnitType <= h264reader.NalUnitTypeAUD:
+			// parameter sets and delimiters belong to the next picture
+			if hasPicture {
+				reader.next = nal
+				return frame, nil
+			}
+		}
+		frame = append(frame, annexBStartCode...)
+		frame = append(frame, nal.Data...)
+	}
+}
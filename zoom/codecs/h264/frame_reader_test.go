@@ -0,0 +1,44 @@
+package h264
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestAnnexBReader(t *testing.T) {
+	sps := []byte{0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1e}
+	pps := []byte{0, 0, 0, 1, 0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0, 0, 0, 1, 0x65, 0x88, 0x84}
+	slice := []byte{0, 0, 0, 1, 0x41, 0x9a, 0x02}
+	// first_mb_in_slice != 0, the second half of the same picture
+	secondSlice := []byte{0, 0, 0, 1, 0x41, 0x5a, 0x03}
+
+	stream := bytes.Join([][]byte{sps, pps, idr, slice, secondSlice, slice, sps, pps, idr}, nil)
+	reader, err := NewAnnexBReader(bytes.NewReader(stream))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := [][]byte{
+		bytes.Join([][]byte{sps, pps, idr}, nil),
+		bytes.Join([][]byte{slice, secondSlice}, nil),
+		slice,
+		bytes.Join([][]byte{sps, pps, idr}, nil),
+	}
+	for i, frame := range expected {
+		read, err := reader.ReadFrame()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !bytes.Equal(read, frame) {
+			t.Errorf("frame %v: expected %x, got %x", i, frame, read)
+		}
+	}
+	_, err = reader.ReadFrame()
+	if err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package h264
+
+const (
+	NALU_TYPE_IDR = 5
+	NALU_TYPE_SPS = 7
+	NALU_TYPE_PPS = 8
+)
+
+// SplitAnnexB returns the NAL units in an annex B byte stream without their start codes
+func SplitAnnexB(data []byte) [][]byte {
+	nalus := make([][]byte, 0)
+	start := -1
+	for i := 0; i+2 < len(data); i++ {
+		// 00 00 01, 4 byte start codes end the same way
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+		if start >= 0 {
+			end := i
+			if end > start && data[end-1] == 0 {
+				end--
+			}
+			nalus = append(nalus, data[start:end])
+		}
+		start = i + 3
+		i += 2
+	}
+	if start >= 0 && start < len(data) {
+		nalus = append(nalus, data[start:])
+	}
+	return nalus
+}
+
+// FindNalu returns the first NAL unit of the given type in an annex B byte stream, or nil
+func FindNalu(data []byte, naluType byte) []byte {
+	for _, nalu := range SplitAnnexB(data) {
+		if len(nalu) > 0 && nalu[0]&MASK_NALU_HEADER_TYPE == naluType {
+			return nalu
+		}
+	}
+	return nil
+}
+
+// IsKeyFrame checks whether a decoder can start from this access unit, that is whether it has an IDR slice
+func IsKeyFrame(data []byte) bool {
+	return FindNalu(data, NALU_TYPE_IDR) != nil
+}
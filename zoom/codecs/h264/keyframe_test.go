@@ -0,0 +1,42 @@
+package h264
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitAnnexB(t *testing.T) {
+	stream := []byte{0, 0, 0, 1, 0x67, 0xAA, 0, 0, 1, 0x68, 0xBB, 0, 0, 0, 1, 0x65, 0xCC, 0xDD}
+	nalus := SplitAnnexB(stream)
+	if len(nalus) != 3 {
+		t.Errorf("expected 3 nalus, got %v", len(nalus))
+		return
+	}
+	if !bytes.Equal(nalus[0], []byte{0x67, 0xAA}) || !bytes.Equal(nalus[1], []byte{0x68, 0xBB}) || !bytes.Equal(nalus[2], []byte{0x65, 0xCC, 0xDD}) {
+		t.Errorf("unexpected nalus %x", nalus)
+	}
+}
+
+func TestIsKeyFrame(t *testing.T) {
+	idr := []byte{0, 0, 0, 1, 0x67, 0xAA, 0, 0, 0, 1, 0x65, 0xCC}
+	if !IsKeyFrame(idr) {
+		t.Error("expected an IDR access unit to be a keyframe")
+	}
+	nonIdr := []byte{0, 0, 0, 1, 0x41, 0xCC}
+	if IsKeyFrame(nonIdr) {
+		t.Error("expected a non IDR slice not to be a keyframe")
+	}
+}
+
+func TestParseSPS(t *testing.T) {
+	// baseline 640x360 encoded by x264, 368 lines cropped to 360
+	nalu := []byte{0x67, 0x42, 0xc0, 0x1e, 0xda, 0x02, 0x80, 0xbf, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x58, 0xba, 0x80}
+	sps, err := ParseSPS(nalu)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if sps.Width != 640 || sps.Height != 360 {
+		t.Errorf("expected 640x360, got %vx%v", sps.Width, sps.Height)
+	}
+}
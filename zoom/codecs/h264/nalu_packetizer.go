@@ -2,7 +2,6 @@ package h264
 
 import (
 	"fmt"
-	"log"
 	"sync"
 )
 
@@ -122,7 +121,6 @@ func (parser *NaluPacketizer) Unmarshal(data []byte) ([]byte, error) {
 func (parser *NaluPacketizer) Marshal(payload []byte) ([][]byte, error) {
 	pkts := make([][]byte, 0)
 	if len(payload) > MTU_MAX {
-		// TODO: fragment
 		bytesWritten := 0
 		for bytesWritten < len(payload) {
 			cursor := bytesWritten
@@ -141,7 +139,6 @@ func (parser *NaluPacketizer) Marshal(payload []byte) ([][]byte, error) {
 				// Set end bit
 				header |= MASK_FU_HEADER_END_BIT
 			}
-			log.Printf("header=%v", header)
 
 			fragment := payload[cursor:end]
 			encoded := append([]byte{FU_A, header}, fragment...)
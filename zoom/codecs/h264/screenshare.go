@@ -0,0 +1,134 @@
+package h264
+
+import (
+	"encoding/binary"
+)
+
+const (
+	// H.264 annex G (SVC) and annex H (MVC), plain decoders don't know these
+	NALU_TYPE_PREFIX          = 14
+	NALU_TYPE_SUBSET_SPS      = 15
+	NALU_TYPE_SLICE_EXTENSION = 20
+	NALU_TYPE_SLICE_3D        = 21
+)
+
+/*
+ScreenShareNormalizer turns decrypted screenshare frames into plain annex B H.264 that ffmpeg and the
+recorders can play, use one per SSRC.
+
+Our guess is that zoom encodes screenshares with an SVC capable encoder and that this is what made them look
+like a custom codec. We have no capture to confirm it, so the normalizer covers the cases that theory suggests:
+
+  - frames may come either in annex B or with 4 byte big endian length prefixes (AVCC), both end up as annex B
+    with 4 byte start codes
+  - SVC NAL units (prefix NAL units, subset SPS and slice extensions) are dropped, what remains would be the
+    AVC compatible base layer. Temporal layers (RtpExtFrameInfo.TemporalID) are plain H.264 and kept.
+  - SPS and PPS may not come with every IDR. The last ones are remembered and put in front of IDRs that lack
+    them so every keyframe can be decoded on its own.
+
+Frames that can't be decoded yet, because no SPS and PPS were seen, are dropped.
+*/
+type ScreenShareNormalizer struct {
+	sps []byte
+	pps []byte
+}
+
+func NewScreenShareNormalizer() *ScreenShareNormalizer {
+	return &ScreenShareNormalizer{}
+}
+
+// Normalize returns the frame as annex B, or nil if there is nothing a decoder could use in it
+func (normalizer *ScreenShareNormalizer) Normalize(frame []byte) ([]byte, error) {
+	nalus, err := splitFrame(frame)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalized []byte
+	hasSps, hasPps, hasSlice := false, false, false
+	for _, nalu := range nalus {
+		if len(nalu) == 0 || nalu[0]&MASK_NALU_HEADER_FORBIDDEN_BIT != 0 {
+			continue
+		}
+		naluType := nalu[0] & MASK_NALU_HEADER_TYPE
+		switch naluType {
+		case NALU_TYPE_PREFIX, NALU_TYPE_SUBSET_SPS, NALU_TYPE_SLICE_EXTENSION, NALU_TYPE_SLICE_3D:
+			continue
+		case NALU_TYPE_SPS:
+			normalizer.sps = append([]byte{}, nalu...)
+			hasSps = true
+		case NALU_TYPE_PPS:
+			normalizer.pps = append([]byte{}, nalu...)
+			hasPps = true
+		case NALU_TYPE_IDR:
+			if !hasSps || !hasPps {
+				if normalizer.sps == nil || normalizer.pps == nil {
+					return nil, nil
+				}
+				if !hasSps {
+					normalized = appendNalu(normalized, normalizer.sps)
+					hasSps = true
+				}
+				if !hasPps {
+					normalized = appendNalu(normalized, normalizer.pps)
+					hasPps = true
+				}
+			}
+			hasSlice = true
+		default:
+			// non-IDR slices and data partitions, nothing before the first parameter sets can be decoded
+			if naluType < NALU_TYPE_IDR {
+				if normalizer.sps == nil || normalizer.pps == nil {
+					return nil, nil
+				}
+				hasSlice = true
+			}
+		}
+		normalized = appendNalu(normalized, nalu)
+	}
+
+	if !hasSlice && !hasSps && !hasPps {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+func appendNalu(data []byte, nalu []byte) []byte {
+	data = append(data, annexBStartCode...)
+	return append(data, nalu...)
+}
+
+/*
+splitFrame returns the NAL units of a frame in annex B or AVCC framing. AVCC is tried first: a first NAL unit
+of 256 to 511 bytes has a length prefix of 00 00 01 xx, which looks just like an annex B start code. A frame is
+only taken as AVCC if its length prefixes add up to exactly the whole frame, annex B almost never does.
+*/
+func splitFrame(frame []byte) ([][]byte, error) {
+	if len(frame) == 0 {
+		return nil, ErrNoData
+	}
+	if nalus, ok := splitAVCC(frame); ok {
+		return nalus, nil
+	}
+	if len(frame) >= 3 && frame[0] == 0 && frame[1] == 0 && (frame[2] == 1 || (len(frame) >= 4 && frame[2] == 0 && frame[3] == 1)) {
+		return SplitAnnexB(frame), nil
+	}
+	return nil, ErrInvalidFraming
+}
+
+// splitAVCC reports whether the frame is a valid chain of 4 byte length prefixed NAL units
+func splitAVCC(frame []byte) ([][]byte, bool) {
+	nalus := make([][]byte, 0)
+	for len(frame) > 0 {
+		if len(frame) < 4 {
+			return nil, false
+		}
+		length := binary.BigEndian.Uint32(frame[0:4])
+		if length == 0 || uint64(length) > uint64(len(frame)-4) {
+			return nil, false
+		}
+		nalus = append(nalus, frame[4:4+length])
+		frame = frame[4+length:]
+	}
+	return nalus, true
+}
@@ -0,0 +1,79 @@
+package h264
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScreenShareNormalizer(t *testing.T) {
+	normalizer := NewScreenShareNormalizer()
+
+	// slices before the first parameter sets are useless
+	frame, err := normalizer.Normalize([]byte{0, 0, 0, 1, 0x41, 0xCC})
+	if err != nil || frame != nil {
+		t.Errorf("expected the slice to be dropped, got %x %v", frame, err)
+		return
+	}
+
+	// length prefixed with a prefix NAL unit and a slice extension of the enhancement layer
+	frame, err = normalizer.Normalize([]byte{
+		0, 0, 0, 2, 0x67, 0xAA,
+		0, 0, 0, 2, 0x68, 0xBB,
+		0, 0, 0, 2, 0x6E, 0x01,
+		0, 0, 0, 2, 0x65, 0xCC,
+		0, 0, 0, 2, 0x74, 0xDD,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := []byte{0, 0, 0, 1, 0x67, 0xAA, 0, 0, 0, 1, 0x68, 0xBB, 0, 0, 0, 1, 0x65, 0xCC}
+	if !bytes.Equal(frame, expected) {
+		t.Errorf("expected %x, got %x", expected, frame)
+	}
+
+	// later IDRs get the parameter sets they lack, 3 byte start codes become 4 byte ones
+	frame, err = normalizer.Normalize([]byte{0, 0, 1, 0x65, 0xEE})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected = []byte{0, 0, 0, 1, 0x67, 0xAA, 0, 0, 0, 1, 0x68, 0xBB, 0, 0, 0, 1, 0x65, 0xEE}
+	if !bytes.Equal(frame, expected) {
+		t.Errorf("expected %x, got %x", expected, frame)
+	}
+
+	// only enhancement layer, nothing left
+	frame, err = normalizer.Normalize([]byte{0, 0, 0, 1, 0x6E, 0x01, 0, 0, 0, 1, 0x74, 0xDD})
+	if err != nil || frame != nil {
+		t.Errorf("expected the enhancement layer to be dropped, got %x %v", frame, err)
+	}
+
+	_, err = normalizer.Normalize([]byte{0, 0, 0, 9, 0x41})
+	if err != ErrInvalidFraming {
+		t.Errorf("expected ErrInvalidFraming, got %v", err)
+	}
+}
+
+func TestScreenShareNormalizerLongAVCCNalu(t *testing.T) {
+	normalizer := NewScreenShareNormalizer()
+	_, err := normalizer.Normalize([]byte{0, 0, 0, 2, 0x67, 0xAA, 0, 0, 0, 2, 0x68, 0xBB})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// a 300 byte slice has the length prefix 00 00 01 2C, which looks like an annex B start code
+	slice := make([]byte, 300)
+	slice[0] = 0x41
+	frame := append([]byte{0, 0, 0x01, 0x2C}, slice...)
+	normalized, err := normalizer.Normalize(frame)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := append([]byte{0, 0, 0, 1}, slice...)
+	if !bytes.Equal(normalized, expected) {
+		t.Errorf("expected the slice in annex B, got %x", normalized)
+	}
+}
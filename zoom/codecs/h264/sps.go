@@ -0,0 +1,173 @@
+package h264
+
+import "errors"
+
+var ErrInvalidSPS = errors.New("invalid SPS")
+
+// SPS holds the few fields of a sequence parameter set we need for muxing
+type SPS struct {
+	ProfileIdc byte
+	LevelIdc   byte
+	Width      int
+	Height     int
+}
+
+// removeEmulationPrevention turns the NAL unit payload back into the raw byte sequence payload (00 00 03 -> 00 00)
+func removeEmulationPrevention(data []byte) []byte {
+	rbsp := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (reader *bitReader) bit() uint {
+	if reader.pos >= len(reader.data)*8 {
+		reader.err = ErrInvalidSPS
+		return 0
+	}
+	b := reader.data[reader.pos/8] >> (7 - uint(reader.pos%8)) & 1
+	reader.pos++
+	return uint(b)
+}
+
+func (reader *bitReader) bits(n int) uint {
+	value := uint(0)
+	for i := 0; i < n; i++ {
+		value = value<<1 | reader.bit()
+	}
+	return value
+}
+
+// exp-golomb
+func (reader *bitReader) ue() uint {
+	leadingZeros := 0
+	for reader.bit() == 0 {
+		if reader.err != nil || leadingZeros > 31 {
+			reader.err = ErrInvalidSPS
+			return 0
+		}
+		leadingZeros++
+	}
+	return (1 << uint(leadingZeros)) - 1 + reader.bits(leadingZeros)
+}
+
+func (reader *bitReader) se() int {
+	value := reader.ue()
+	if value%2 == 0 {
+		return -int(value / 2)
+	}
+	return int(value+1) / 2
+}
+
+// ParseSPS reads the resolution from a SPS NAL unit (including its header byte), see ITU-T H.264 7.3.2.1.1
+func ParseSPS(nalu []byte) (*SPS, error) {
+	if len(nalu) < 4 || nalu[0]&MASK_NALU_HEADER_TYPE != NALU_TYPE_SPS {
+		return nil, ErrInvalidSPS
+	}
+	reader := &bitReader{data: removeEmulationPrevention(nalu[1:])}
+
+	sps := &SPS{}
+	sps.ProfileIdc = byte(reader.bits(8))
+	reader.bits(8) // constraint flags
+	sps.LevelIdc = byte(reader.bits(8))
+	reader.ue() // seq_parameter_set_id
+
+	chromaFormatIdc := uint(1)
+	switch sps.ProfileIdc {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chromaFormatIdc = reader.ue()
+		if chromaFormatIdc == 3 {
+			reader.bit() // separate_colour_plane_flag
+		}
+		reader.ue()  // bit_depth_luma_minus8
+		reader.ue()  // bit_depth_chroma_minus8
+		reader.bit() // qpprime_y_zero_transform_bypass_flag
+		if reader.bit() == 1 {
+			scalingLists := 8
+			if chromaFormatIdc == 3 {
+				scalingLists = 12
+			}
+			for i := 0; i < scalingLists; i++ {
+				if reader.bit() == 0 {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				lastScale, nextScale := 8, 8
+				for j := 0; j < size; j++ {
+					if nextScale != 0 {
+						nextScale = (lastScale + reader.se() + 256) % 256
+					}
+					if nextScale != 0 {
+						lastScale = nextScale
+					}
+				}
+			}
+		}
+	}
+
+	reader.ue() // log2_max_frame_num_minus4
+	picOrderCntType := reader.ue()
+	if picOrderCntType == 0 {
+		reader.ue() // log2_max_pic_order_cnt_lsb_minus4
+	} else if picOrderCntType == 1 {
+		reader.bit() // delta_pic_order_always_zero_flag
+		reader.se()  // offset_for_non_ref_pic
+		reader.se()  // offset_for_top_to_bottom_field
+		cycle := reader.ue()
+		for i := uint(0); i < cycle && reader.err == nil; i++ {
+			reader.se()
+		}
+	}
+	reader.ue()  // max_num_ref_frames
+	reader.bit() // gaps_in_frame_num_value_allowed_flag
+	widthInMbs := reader.ue() + 1
+	heightInMapUnits := reader.ue() + 1
+	frameMbsOnly := reader.bit()
+	if frameMbsOnly == 0 {
+		reader.bit() // mb_adaptive_frame_field_flag
+	}
+	reader.bit() // direct_8x8_inference_flag
+
+	var cropLeft, cropRight, cropTop, cropBottom uint
+	if reader.bit() == 1 {
+		cropLeft = reader.ue()
+		cropRight = reader.ue()
+		cropTop = reader.ue()
+		cropBottom = reader.ue()
+	}
+	if reader.err != nil {
+		return nil, reader.err
+	}
+
+	cropUnitX, cropUnitY := uint(1), 2-frameMbsOnly
+	switch chromaFormatIdc {
+	case 1:
+		cropUnitX, cropUnitY = 2, 2*(2-frameMbsOnly)
+	case 2:
+		cropUnitX = 2
+	}
+
+	sps.Width = int(widthInMbs*16 - (cropLeft+cropRight)*cropUnitX)
+	sps.Height = int((2-frameMbsOnly)*heightInMapUnits*16 - (cropTop+cropBottom)*cropUnitY)
+	return sps, nil
+}
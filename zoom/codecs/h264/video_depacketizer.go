@@ -32,7 +32,6 @@ func (depacketizer *VideoDepacketizer) Unmarshal(packet []byte) ([]byte, error)
 		decodedPayload := &crypto.RtpEncryptedPayload{}
 		err = decodedPayload.Unmarshal(naluStream)
 		if err != nil {
-			log.Fatal(err)
 			return nil, err
 		}
 
@@ -63,7 +62,7 @@ func (*VideoDepacketizer) IsPartitionHead(payload []byte) bool {
 	} else if isFragmented(payload[0]) {
 		return isFragmentedStart(payload[1])
 	} else {
-		log.Fatalf("H264Depacketizer IsPartitionHead received invalid payload = %v", hex.EncodeToString(payload))
+		log.Printf("H264Depacketizer IsPartitionHead received invalid payload = %v", hex.EncodeToString(payload))
 	}
 	return false
 }
@@ -86,7 +85,7 @@ func (*VideoDepacketizer) IsPartitionTail(marker bool, payload []byte) bool {
 		}
 		return fragmentedEnd
 	} else {
-		log.Fatalf("H264Depacketizer IsPartitionTail received invalid payload = %v", hex.EncodeToString(payload))
+		log.Printf("H264Depacketizer IsPartitionTail received invalid payload = %v", hex.EncodeToString(payload))
 	}
 	return false
 }
@@ -22,7 +22,6 @@ func (depacketizer *AudioDepacketizer) Unmarshal(packet []byte) ([]byte, error)
 	decodedPayload := &crypto.RtpEncryptedPayload{}
 	err := decodedPayload.Unmarshal(packet)
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
 
@@ -1,33 +1,62 @@
 package opus
 
 import (
-	"io"
+	"encoding/binary"
+	"fmt"
+	"log"
 	"os"
+	"sync"
 	"time"
-
-	"github.com/pion/webrtc/v3/pkg/media"
 )
 
+// PCMRecorder is an AudioSink that writes raw s16le PCM into a file per participant, play them back with
+// ffplay -f s16le -ar 48000 -ac 1 <file>
 type PCMRecorder struct {
-	rawFile io.WriteCloser
+	prefix string
+
+	mu    sync.Mutex
+	files map[ /*userId*/ int]*os.File
 }
 
 func CreateNewPCMRecorder() (*PCMRecorder, error) {
-	fileName := time.Now().Format("2006-01-02-15-04-05") + ".raw"
-	f, err := os.Create(fileName)
-	if err != nil {
-		return nil, err
-	}
 	return &PCMRecorder{
-		rawFile: f,
+		prefix: time.Now().Format("2006-01-02-15-04-05"),
+		files:  make(map[int]*os.File),
 	}, nil
 }
 
-func (recorder *PCMRecorder) Record(sample *media.Sample) error {
-	_, err := recorder.rawFile.Write(sample.Data)
-	return err
+func (recorder *PCMRecorder) OnPCM(userID int, samples []int16, timestamp uint32) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	f := recorder.files[userID]
+	if f == nil {
+		var err error
+		f, err = os.Create(fmt.Sprintf("%s-%d.raw", recorder.prefix, userID))
+		if err != nil {
+			log.Printf("Creating PCM recording for %v failed: %+v", userID, err)
+			return
+		}
+		recorder.files[userID] = f
+	}
+
+	err := binary.Write(f, binary.LittleEndian, samples)
+	if err != nil {
+		log.Printf("Writing PCM recording for %v failed: %+v", userID, err)
+	}
 }
 
 func (recorder *PCMRecorder) Close() error {
-	return recorder.rawFile.Close()
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	var firstErr error
+	for userID, f := range recorder.files {
+		err := f.Close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(recorder.files, userID)
+	}
+	return firstErr
 }
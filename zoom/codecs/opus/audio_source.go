@@ -0,0 +1,102 @@
+package opus
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var ErrInvalidPacket = errors.New("opus packet is invalid")
+
+// AudioSource hands out opus packets to publish, it returns io.EOF once there is nothing left
+type AudioSource interface {
+	ReadPacket() ([]byte, error)
+}
+
+/*
+Encoder turns PCM_SAMPLE_RATE mono PCM into a single opus packet. There is no pure Go opus encoder yet, so
+bring your own, e.g. github.com/hraban/opus created with opus.NewEncoder(PCM_SAMPLE_RATE, PCM_CHANNELS, opus.AppVoIP)
+*/
+type Encoder interface {
+	Encode(pcm []int16, data []byte) (int, error)
+}
+
+// PCMSource encodes raw s16le PCM at PCM_SAMPLE_RATE into 20ms opus packets
+type PCMSource struct {
+	reader  io.Reader
+	encoder Encoder
+	buffer  []byte
+	frame   []int16
+	packet  []byte
+}
+
+func NewPCMSource(reader io.Reader, encoder Encoder) *PCMSource {
+	return &PCMSource{
+		reader:  reader,
+		encoder: encoder,
+		buffer:  make([]byte, MIXER_FRAME_SIZE*2),
+		frame:   make([]int16, MIXER_FRAME_SIZE),
+		packet:  make([]byte, 4000),
+	}
+}
+
+func (source *PCMSource) ReadPacket() ([]byte, error) {
+	n, err := io.ReadFull(source.reader, source.buffer)
+	if err == io.ErrUnexpectedEOF {
+		// pad the last frame with silence
+		for i := n; i < len(source.buffer); i++ {
+			source.buffer[i] = 0
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range source.frame {
+		source.frame[i] = int16(binary.LittleEndian.Uint16(source.buffer[i*2:]))
+	}
+
+	n, err = source.encoder.Encode(source.frame, source.packet)
+	if err != nil {
+		return nil, err
+	}
+	return source.packet[:n], nil
+}
+
+// PacketSamples returns how many samples per channel at 48kHz an opus packet holds, see RFC 6716 section 3.1
+func PacketSamples(packet []byte) (int, error) {
+	if len(packet) < 1 {
+		return 0, ErrInvalidPacket
+	}
+
+	config := packet[0] >> 3
+	var frameSize int
+	switch {
+	case config < 12:
+		// SILK 10, 20, 40, 60ms
+		frameSize = []int{480, 960, 1920, 2880}[config%4]
+	case config < 16:
+		// hybrid 10, 20ms
+		frameSize = []int{480, 960}[config%2]
+	default:
+		// CELT 2.5, 5, 10, 20ms
+		frameSize = []int{120, 240, 480, 960}[config%4]
+	}
+
+	frames := 1
+	switch packet[0] & 0x03 {
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, ErrInvalidPacket
+		}
+		frames = int(packet[1] & 0x3F)
+	}
+	// RFC 6716 section 3.2.5, a packet holds at least one frame and at most 120ms
+	samples := frameSize * frames
+	if frames == 0 || samples > PCM_SAMPLE_RATE/1000*120 {
+		return 0, ErrInvalidPacket
+	}
+	return samples, nil
+}
@@ -0,0 +1,204 @@
+package opus
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// the mix is handed to the sink as this user
+	MIXED_USER_ID = 0
+
+	MIXER_FRAME_SIZE = PCM_SAMPLE_RATE / 50 * PCM_CHANNELS
+	// how long the mixer waits for late packets before a frame is mixed
+	DEFAULT_JITTER_DELAY = 200 * time.Millisecond
+
+	// packets this far off are not jitter anymore, the sender's clock jumped
+	maxDrift = PCM_SAMPLE_RATE * 2 * PCM_CHANNELS
+)
+
+type mixerParticipant struct {
+	anchored bool
+	// maps the rtp timestamps of a participant onto the position in the mix
+	offset   int64
+	last     uint32
+	extended int64
+}
+
+// position of the sample with this rtp timestamp in the mix, rtp timestamps wrap so they are extended first
+func (participant *mixerParticipant) position(timestamp uint32) int64 {
+	participant.extended += int64(int32(timestamp - participant.last))
+	participant.last = timestamp
+	return participant.extended + participant.offset
+}
+
+/*
+AudioMixer is an AudioSink that mixes the audio of all participants into a single 48kHz mono track, e.g. for
+recordings or transcription where it doesn't matter who said what.
+
+Every participant has their own rtp clock, the first packet of someone is lined up with the time it arrived
+and the packets after that are placed by their rtp timestamp. Frames of MIXER_FRAME_SIZE samples are mixed
+once they are older than the jitter delay, packets arriving after their frame was mixed are dropped. Silence
+is filled in when nobody talks so the mix keeps up with the wall clock.
+
+The mix is passed on as raw PCM with the user MIXED_USER_ID and the position in samples as timestamp, there is
+no Opus encoder around to turn it back into packets.
+*/
+type AudioMixer struct {
+	sink        AudioSink
+	jitterDelay time.Duration
+	now         func() time.Time
+	done        chan struct{}
+	wg          sync.WaitGroup
+
+	mu           sync.Mutex
+	start        time.Time
+	participants map[ /*userId*/ int]*mixerParticipant
+	// position of the first sample in pending
+	mixed   int64
+	pending []int32
+	frame   []int16
+}
+
+// NewAudioMixer starts mixing into the sink, pass a jitter delay of 0 to use DEFAULT_JITTER_DELAY
+func NewAudioMixer(sink AudioSink, jitterDelay time.Duration) *AudioMixer {
+	mixer := newAudioMixer(sink, jitterDelay, time.Now)
+	mixer.wg.Add(1)
+	go mixer.run()
+	return mixer
+}
+
+func newAudioMixer(sink AudioSink, jitterDelay time.Duration, now func() time.Time) *AudioMixer {
+	if jitterDelay <= 0 {
+		jitterDelay = DEFAULT_JITTER_DELAY
+	}
+	return &AudioMixer{
+		sink:         sink,
+		jitterDelay:  jitterDelay,
+		now:          now,
+		done:         make(chan struct{}),
+		start:        now(),
+		participants: make(map[int]*mixerParticipant),
+		frame:        make([]int16, MIXER_FRAME_SIZE),
+	}
+}
+
+func (mixer *AudioMixer) run() {
+	defer mixer.wg.Done()
+
+	ticker := time.NewTicker(time.Second / 50)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			mixer.flush(mixer.position(mixer.now()) - mixer.samples(mixer.jitterDelay))
+		case <-mixer.done:
+			return
+		}
+	}
+}
+
+func (mixer *AudioMixer) samples(duration time.Duration) int64 {
+	return int64(duration) * PCM_SAMPLE_RATE / int64(time.Second) * PCM_CHANNELS
+}
+
+// position in the mix matching a wall clock time
+func (mixer *AudioMixer) position(at time.Time) int64 {
+	return mixer.samples(at.Sub(mixer.start))
+}
+
+func (mixer *AudioMixer) OnPCM(userID int, samples []int16, timestamp uint32) {
+	if userID == MIXED_USER_ID {
+		return
+	}
+	now := mixer.position(mixer.now())
+
+	mixer.mu.Lock()
+	defer mixer.mu.Unlock()
+
+	participant := mixer.participants[userID]
+	if participant == nil {
+		participant = &mixerParticipant{last: timestamp}
+		mixer.participants[userID] = participant
+	}
+	at := participant.position(timestamp)
+	end := at + int64(len(samples))
+	if !participant.anchored || end < mixer.mixed-maxDrift || at > now+maxDrift {
+		// new, or their clock drifted so far that they would never be heard again
+		participant.anchored = true
+		participant.offset += now - at
+		at = now
+		end = at + int64(len(samples))
+	}
+	if end <= mixer.mixed {
+		// too late to be mixed
+		return
+	}
+
+	if at < mixer.mixed {
+		// the start of the packet came too late
+		samples = samples[mixer.mixed-at:]
+		at = mixer.mixed
+	}
+	if needed := int(end - mixer.mixed); needed > len(mixer.pending) {
+		mixer.pending = append(mixer.pending, make([]int32, needed-len(mixer.pending))...)
+	}
+	pending := mixer.pending[at-mixer.mixed:]
+	for i, sample := range samples {
+		pending[i] += int32(sample)
+	}
+}
+
+// flush mixes and passes on all complete frames before the position
+func (mixer *AudioMixer) flush(upTo int64) {
+	mixer.mu.Lock()
+	defer mixer.mu.Unlock()
+
+	for mixer.mixed+MIXER_FRAME_SIZE <= upTo {
+		for i := range mixer.frame {
+			var sample int32
+			if i < len(mixer.pending) {
+				sample = mixer.pending[i]
+			}
+			mixer.frame[i] = clip(sample)
+		}
+		mixer.sink.OnPCM(MIXED_USER_ID, mixer.frame, uint32(mixer.mixed))
+
+		if len(mixer.pending) > MIXER_FRAME_SIZE {
+			mixer.pending = append(mixer.pending[:0], mixer.pending[MIXER_FRAME_SIZE:]...)
+		} else {
+			mixer.pending = mixer.pending[:0]
+		}
+		mixer.mixed += MIXER_FRAME_SIZE
+	}
+}
+
+func clip(sample int32) int16 {
+	if sample > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if sample < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(sample)
+}
+
+// RemoveParticipant forgets the clock of someone who left, what they said is still mixed
+func (mixer *AudioMixer) RemoveParticipant(userID int) {
+	mixer.mu.Lock()
+	defer mixer.mu.Unlock()
+	delete(mixer.participants, userID)
+}
+
+// Close stops the mixer and passes on everything that was received
+func (mixer *AudioMixer) Close() {
+	close(mixer.done)
+	mixer.wg.Wait()
+
+	mixer.mu.Lock()
+	end := mixer.mixed + int64(len(mixer.pending))
+	mixer.mu.Unlock()
+	// round up to a whole frame
+	mixer.flush(end + MIXER_FRAME_SIZE - 1)
+}
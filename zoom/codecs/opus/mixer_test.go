@@ -0,0 +1,65 @@
+package opus
+
+import (
+	"testing"
+	"time"
+)
+
+type mixCapture struct {
+	samples []int16
+}
+
+func (capture *mixCapture) OnPCM(userID int, samples []int16, timestamp uint32) {
+	if userID != MIXED_USER_ID || int(timestamp) != len(capture.samples) {
+		return
+	}
+	capture.samples = append(capture.samples, samples...)
+}
+
+func constant(value int16, count int) []int16 {
+	samples := make([]int16, count)
+	for i := range samples {
+		samples[i] = value
+	}
+	return samples
+}
+
+func TestAudioMixer(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	capture := &mixCapture{}
+	mixer := newAudioMixer(capture, 40*time.Millisecond, func() time.Time {
+		return now
+	})
+
+	// 1 starts talking right away, 2 joins 20ms later with a completely different rtp clock
+	mixer.OnPCM(1, constant(1000, MIXER_FRAME_SIZE), 5000)
+	now = now.Add(20 * time.Millisecond)
+	mixer.OnPCM(2, constant(31000, MIXER_FRAME_SIZE), 4000000000)
+	// 1 arrives out of order
+	mixer.OnPCM(1, constant(3000, MIXER_FRAME_SIZE), 5000+2*MIXER_FRAME_SIZE)
+	mixer.OnPCM(1, constant(2000, MIXER_FRAME_SIZE), 5000+MIXER_FRAME_SIZE)
+	now = now.Add(40 * time.Millisecond)
+	mixer.flush(mixer.position(now) - mixer.samples(mixer.jitterDelay))
+
+	if len(capture.samples) != MIXER_FRAME_SIZE {
+		t.Errorf("expected a single frame before the jitter delay, got %v samples", len(capture.samples))
+		return
+	}
+	// too late, the first frame is already mixed
+	mixer.OnPCM(2, constant(1, MIXER_FRAME_SIZE), 4000000000-MIXER_FRAME_SIZE)
+	mixer.Close()
+
+	expected := []int16{1000, 32767, 3000}
+	if len(capture.samples) != len(expected)*MIXER_FRAME_SIZE {
+		t.Errorf("expected %v frames, got %v samples", len(expected), len(capture.samples))
+		return
+	}
+	for i, value := range expected {
+		for _, sample := range capture.samples[i*MIXER_FRAME_SIZE : (i+1)*MIXER_FRAME_SIZE] {
+			if sample != value {
+				t.Errorf("expected frame %v to be %v, got %v", i, value, sample)
+				break
+			}
+		}
+	}
+}
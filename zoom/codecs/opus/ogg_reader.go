@@ -0,0 +1,93 @@
+package opus
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var ErrNotOggOpus = errors.New("not an ogg opus stream")
+
+const oggPageHeaderLength = 27
+
+/*
+OggReader is an AudioSource for .opus/.ogg files, e.g. made with ffmpeg -i in.mp3 -ac 1 -c:a libopus out.opus
+
+Unlike pion's oggreader it splits pages into packets, ffmpeg puts up to a second of packets into a single page.
+Only single stream files are supported, the sample rate of the file doesn't matter since opus always decodes at 48kHz.
+*/
+type OggReader struct {
+	reader io.Reader
+	// packets of the current page that were not read yet
+	packets [][]byte
+	// a packet continuing on the next page
+	partial []byte
+	headers int
+}
+
+func NewOggReader(reader io.Reader) *OggReader {
+	return &OggReader{
+		reader: reader,
+	}
+}
+
+func (ogg *OggReader) ReadPacket() ([]byte, error) {
+	for {
+		for len(ogg.packets) == 0 {
+			err := ogg.readPage()
+			if err != nil {
+				return nil, err
+			}
+		}
+		packet := ogg.packets[0]
+		ogg.packets = ogg.packets[1:]
+
+		// the first two packets are the OpusHead and OpusTags headers
+		if ogg.headers < 2 {
+			if ogg.headers == 0 && !bytes.HasPrefix(packet, []byte("OpusHead")) {
+				return nil, ErrNotOggOpus
+			}
+			ogg.headers++
+			continue
+		}
+		return packet, nil
+	}
+}
+
+func (ogg *OggReader) readPage() error {
+	header := make([]byte, oggPageHeaderLength)
+	_, err := io.ReadFull(ogg.reader, header)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(header[0:4], []byte("OggS")) {
+		return ErrNotOggOpus
+	}
+
+	lacing := make([]byte, header[26])
+	_, err = io.ReadFull(ogg.reader, lacing)
+	if err != nil {
+		return err
+	}
+	payloadSize := 0
+	for _, size := range lacing {
+		payloadSize += int(size)
+	}
+	payload := make([]byte, payloadSize)
+	_, err = io.ReadFull(ogg.reader, payload)
+	if err != nil {
+		return err
+	}
+
+	// a lacing value below 255 ends a packet, 255 means it goes on in the next segment
+	offset := 0
+	for _, size := range lacing {
+		ogg.partial = append(ogg.partial, payload[offset:offset+int(size)]...)
+		offset += int(size)
+		if size < 255 {
+			ogg.packets = append(ogg.packets, ogg.partial)
+			ogg.partial = nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package opus
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// oggPage builds a page without a valid checksum, the reader doesn't check it
+func oggPage(lacing []byte, payload ...[]byte) []byte {
+	header := make([]byte, oggPageHeaderLength)
+	copy(header, "OggS")
+	header[26] = byte(len(lacing))
+	page := append(header, lacing...)
+	for _, data := range payload {
+		page = append(page, data...)
+	}
+	return page
+}
+
+func TestOggReader(t *testing.T) {
+	head := append([]byte("OpusHead"), make([]byte, 11)...)
+	tags := append([]byte("OpusTags"), make([]byte, 8)...)
+	short := []byte{0xF8, 0xFF, 0xFE}
+	long := bytes.Repeat([]byte{0xFC}, 300)
+	split := bytes.Repeat([]byte{0x78}, 255)
+
+	var file []byte
+	file = append(file, oggPage([]byte{19}, head)...)
+	file = append(file, oggPage([]byte{16}, tags)...)
+	file = append(file, oggPage([]byte{3, 255, 45, 255}, short, long, split)...)
+	// the packet continues on this page and ends with a zero length segment
+	file = append(file, oggPage([]byte{0, 3}, short)...)
+
+	reader := NewOggReader(bytes.NewReader(file))
+	expected := [][]byte{short, long, split, short}
+	for i, packet := range expected {
+		read, err := reader.ReadPacket()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !bytes.Equal(read, packet) {
+			t.Errorf("packet %v: expected %v bytes, got %v", i, len(packet), len(read))
+		}
+	}
+	_, err := reader.ReadPacket()
+	if err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+}
+
+func TestPacketSamples(t *testing.T) {
+	packets := map[string][]byte{
+		"CELT 20ms":            {0xF8},
+		"SILK 60ms":            {0x18},
+		"hybrid 2x10ms":        {0x61},
+		"CELT 3x2.5ms (code3)": {0x83, 0x03},
+	}
+	expected := map[string]int{
+		"CELT 20ms":            960,
+		"SILK 60ms":            2880,
+		"hybrid 2x10ms":        960,
+		"CELT 3x2.5ms (code3)": 360,
+	}
+	for name, packet := range packets {
+		samples, err := PacketSamples(packet)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if samples != expected[name] {
+			t.Errorf("%v: expected %v samples, got %v", name, expected[name], samples)
+		}
+	}
+
+	invalid := map[string][]byte{
+		"empty":                {},
+		"code3 without frames": {0x83, 0x00},
+		"code3 over 120ms":     {0x1B, 0x03},
+	}
+	for name, packet := range invalid {
+		_, err := PacketSamples(packet)
+		if err != ErrInvalidPacket {
+			t.Errorf("%v: expected ErrInvalidPacket, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package opus
+
+import (
+	"sync"
+
+	"github.com/pion/opus"
+)
+
+const (
+	PCM_SAMPLE_RATE = 48000
+	PCM_CHANNELS    = 1
+	// a single opus packet holds at most 120ms of audio
+	maxSamplesPerPacket = PCM_SAMPLE_RATE / 1000 * 120 * PCM_CHANNELS
+)
+
+// AudioSink receives the decoded audio of every participant, samples are only valid during the call
+type AudioSink interface {
+	OnPCM(userID int, samples []int16, timestamp uint32)
+}
+
+/*
+PCMDecoder turns the opus packets of each participant into 16-bit mono PCM at PCM_SAMPLE_RATE.
+
+Opus decoders carry state from one packet to the next, so every participant gets their own decoder. Mixing
+the packets of several people into a single decoder garbles all of them.
+*/
+type PCMDecoder struct {
+	sink AudioSink
+
+	mu       sync.Mutex
+	decoders map[ /*userId*/ int]*opus.Decoder
+	buffer   []int16
+}
+
+func NewPCMDecoder(sink AudioSink) *PCMDecoder {
+	return &PCMDecoder{
+		sink:     sink,
+		decoders: make(map[int]*opus.Decoder),
+		buffer:   make([]int16, maxSamplesPerPacket),
+	}
+}
+
+func (decoder *PCMDecoder) getDecoderFor(userID int) (*opus.Decoder, error) {
+	if decoder.decoders[userID] == nil {
+		opusDecoder, err := opus.NewDecoderWithOutput(PCM_SAMPLE_RATE, PCM_CHANNELS)
+		if err != nil {
+			return nil, err
+		}
+		decoder.decoders[userID] = &opusDecoder
+	}
+	return decoder.decoders[userID], nil
+}
+
+// Decode decodes a single opus packet and passes the PCM on to the sink
+func (decoder *PCMDecoder) Decode(userID int, timestamp uint32, packet []byte) error {
+	decoder.mu.Lock()
+	defer decoder.mu.Unlock()
+
+	opusDecoder, err := decoder.getDecoderFor(userID)
+	if err != nil {
+		return err
+	}
+
+	sampleCount, err := opusDecoder.DecodeToInt16(packet, decoder.buffer)
+	if err != nil {
+		return err
+	}
+	decoder.sink.OnPCM(userID, decoder.buffer[:sampleCount*PCM_CHANNELS], timestamp)
+	return nil
+}
+
+// RemoveParticipant drops the decoder state of someone who left
+func (decoder *PCMDecoder) RemoveParticipant(userID int) {
+	decoder.mu.Lock()
+	defer decoder.mu.Unlock()
+	delete(decoder.decoders, userID)
+}
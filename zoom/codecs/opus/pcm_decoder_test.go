@@ -0,0 +1,40 @@
+package opus
+
+import "testing"
+
+type capturingSink struct {
+	users   []int
+	samples []int
+}
+
+func (sink *capturingSink) OnPCM(userID int, samples []int16, timestamp uint32) {
+	sink.users = append(sink.users, userID)
+	sink.samples = append(sink.samples, len(samples))
+}
+
+func TestPCMDecoderPerParticipant(t *testing.T) {
+	// 20ms of fullband CELT silence
+	silence := []byte{0xF8, 0xFF, 0xFE}
+
+	sink := &capturingSink{}
+	decoder := NewPCMDecoder(sink)
+	for _, userID := range []int{16778240, 16779264, 16778240} {
+		err := decoder.Decode(userID, 0, silence)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if len(decoder.decoders) != 2 {
+		t.Errorf("expected a decoder per participant, got %v", len(decoder.decoders))
+	}
+	if len(sink.users) != 3 || sink.users[1] != 16779264 {
+		t.Errorf("unexpected users %v", sink.users)
+	}
+	for _, count := range sink.samples {
+		if count != PCM_SAMPLE_RATE/50 {
+			t.Errorf("expected 20ms of samples, got %v", count)
+		}
+	}
+}
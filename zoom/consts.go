@@ -39,35 +39,35 @@ const (
 	// websocket
 	WS_CONF_JOIN_REQ                                 = 4097
 	WS_CONF_JOIN_RES                                 = 4098 // JoinConferenceResponse
-	WS_CONF_LOCK_REQ                                 = 4099
-	WS_CONF_LOCK_RES                                 = 4100
+	WS_CONF_LOCK_REQ                                 = 4099 // ConferenceLockRequest
+	WS_CONF_LOCK_RES                                 = 4100 // ConferenceLockResponse
 	WS_CONF_END_REQ                                  = 4101 // ConferenceEndRequest
 	WS_CONF_END_RES                                  = 4102
 	WS_CONF_LEAVE_REQ                                = 4103
 	WS_CONF_LEAVE_RES                                = 4104
 	WS_CONF_RECORD_REQ                               = 4105
 	WS_CONF_RECORD_RES                               = 4106
-	WS_CONF_EXPEL_REQ                                = 4107
-	WS_CONF_EXPEL_RES                                = 4108
+	WS_CONF_EXPEL_REQ                                = 4107 // ConferenceExpelRequest
+	WS_CONF_EXPEL_RES                                = 4108 // ConferenceExpelResponse
 	WS_CONF_RENAME_REQ                               = 4109 // ConferenceRenameRequest
-	WS_CONF_ASSIGN_HOST_REQ                          = 4111
-	WS_CONF_PUT_ON_HOLD_REQ                          = 4113
+	WS_CONF_ASSIGN_HOST_REQ                          = 4111 // ConferenceAssignHostRequest
+	WS_CONF_PUT_ON_HOLD_REQ                          = 4113 // ConferencePutOnHoldRequest
 	WS_CONF_SET_MUTE_UPON_ENTRY_REQ                  = 4115 // ConferenceSetMuteUponEntryRequest
-	WS_CONF_SET_HOLD_UPON_ENTRY_REQ                  = 4117
+	WS_CONF_SET_HOLD_UPON_ENTRY_REQ                  = 4117 // ConferenceSetHoldUponEntryRequest
 	WS_CONF_INVITE_CRC_DEVICE_REQ                    = 4119
 	WS_CONF_INVITE_CRC_DEVICE_RES                    = 4120
 	WS_CONF_CANCEL_INVITE_CRC_DEVICE_REQ             = 4121
 	WS_CONF_CANCEL_INVITE_CRC_DEVICE_RES             = 4122
 	WS_CONF_SET_BROADCAST_REQ                        = 4123
 	WS_CONF_SET_BROADCAST_RES                        = 4124
-	WS_CONF_CLOSED_CAPTION_REQ                       = 4125
-	WS_CONF_CLOSED_CAPTION_RES                       = 4126
+	WS_CONF_CLOSED_CAPTION_REQ                       = 4125 // ConferenceClosedCaptionRequest
+	WS_CONF_CLOSED_CAPTION_RES                       = 4126 // ConferenceClosedCaptionResponse
 	WS_CONF_ALLOW_VIEW_PARTICIPANT_REQ               = 4127
 	WS_CONF_LOWER_ALL_HAND_REQ                       = 4129
 	WS_CONF_RAISE_LOWER_HAND_REQ                     = 4131
-	WS_CONF_RECLAIM_HOST_REQ                         = 4133
+	WS_CONF_RECLAIM_HOST_REQ                         = 4133 // ConferenceReclaimHostRequest
 	WS_CONF_CHAT_REQ                                 = 4135 // ConferenceChatRequest
-	WS_CONF_ASSIGN_CC_REQ                            = 4137
+	WS_CONF_ASSIGN_CC_REQ                            = 4137 // ConferenceAssignCaptionerRequest
 	WS_CONF_CHAT_PRIVILEDGE_REQ                      = 4141 // ConferenceChatPrivilegeRequest
 	WS_CONF_FEEDBACK_REQ                             = 4143
 	WS_CONF_FEEDBACK_CLEAR_REQ                       = 4145
@@ -78,36 +78,36 @@ const (
 	WS_CONF_BO_TOKEN_REQ                             = 4173
 	WS_CONF_BO_TOKEN_RES                             = 4174 // ConferenceBreakoutRoomTokenResponse
 	WS_CONF_BO_START_REQ                             = 4175 // ConferenceBreakoutRoomStartRequest
-	WS_CONF_BO_STOP_REQ                              = 4177
-	WS_CONF_BO_ASSIGN_REQ                            = 4179
-	WS_CONF_BO_SWITCH_REQ                            = 4181
+	WS_CONF_BO_STOP_REQ                              = 4177 // ConferenceBreakoutRoomStopRequest
+	WS_CONF_BO_ASSIGN_REQ                            = 4179 // ConferenceBreakoutRoomAssignRequest
+	WS_CONF_BO_SWITCH_REQ                            = 4181 // ConferenceBreakoutRoomSwitchRequest
 	WS_CONF_BO_WANT_JOIN_REQ                         = 4183
-	WS_CONF_BO_LEAVE_REQ                             = 4185
+	WS_CONF_BO_LEAVE_REQ                             = 4185 // ConferenceBreakoutRoomLeaveRequest
 	WS_CONF_BO_BROADCAST_REQ                         = 4187 // ConferenceBreakoutRoomBroadcastRequest
-	WS_CONF_BO_HELP_REQ                              = 4189
+	WS_CONF_BO_HELP_REQ                              = 4189 // ConferenceBreakoutRoomHelpRequest
 	WS_CONF_BO_HELP_RESULT_REQ                       = 4191
 	WS_CONF_BO_JOIN_REQ                              = 4193 // ConferenceBreakoutRoomJoinRequest
 	WS_CONF_BO_JOIN_RES                              = 4194 // ConferenceBreakoutRoomJoinResponse
 	WS_CONF_ALLOW_PARTICIPANT_RENAME_REQ             = 4163 // ConferenceAllowParticipantRenameRequest
 	WS_CONF_ALLOW_MESSAGE_FEEDBACK_NOTIFY_REQ        = 4171
-	WS_CONF_REVOKE_COHOST_REQ                        = 4195
+	WS_CONF_REVOKE_COHOST_REQ                        = 4195 // ConferenceRevokeCoHostRequest
 	WS_CONF_PLAY_CHIME_OPEN_CLOSE_REQ                = 4197
-	WS_CONF_ADMIT_ALL_SILENT_USERS_REQ               = 4199
+	WS_CONF_ADMIT_ALL_SILENT_USERS_REQ               = 4199 // ConferenceAdmitAllSilentUsersRequest
 	WS_CONF_BIND_UNBIND_TELE_USR_REQ                 = 4201
 	WS_CONF_ALLOW_QA_AUTO_REPLY_REQ                  = 4203
-	WS_CONF_EXPEL_ATTENDEE_REQ                       = 4205
-	WS_CONF_EXPEL_ATTENDEE_RES                       = 4206
+	WS_CONF_EXPEL_ATTENDEE_REQ                       = 4205 // ConferenceExpelAttendeeRequest
+	WS_CONF_EXPEL_ATTENDEE_RES                       = 4206 // ConferenceExpelAttendeeResponse
 	WS_CONF_PRACTICE_SESSION_REQ                     = 4207
 	WS_CONF_PRACTICE_SESSION_RES                     = 4208
 	WS_CONF_ROLE_CHANGE_REQ                          = 4209
 	WS_CONF_ROLE_CHANGE_RES                          = 4210
 	WS_CONF_BO_TOKEN_BATCH_REQ                       = 4211 // ConferenceBreakoutRoomTokenBatchRequest
-	WS_CONF_BO_PRE_ASSIGN_REQ                        = 4213
-	WS_CONF_BO_PRE_ASSIGN_RES                        = 4214
+	WS_CONF_BO_PRE_ASSIGN_REQ                        = 4213 // ConferenceBreakoutRoomPreAssignRequest
+	WS_CONF_BO_PRE_ASSIGN_RES                        = 4214 // ConferenceBreakoutRoomPreAssignResponse
 	WS_CONF_CHANGE_MULTI_PIN_PRIVILGE_REQ            = 4217
 	WS_CONF_SET_GROUP_LAYOUT                         = 4219
-	WS_CONF_HOST_KEY_REQ                             = 4215
-	WS_CONF_HOST_KEY_RES                             = 4216
+	WS_CONF_HOST_KEY_REQ                             = 4215 // ConferenceHostKeyRequest
+	WS_CONF_HOST_KEY_RES                             = 4216 // ConferenceHostKeyResponse
 	WS_CONF_AVATAR_PERMISSION_CHANGED                = 4222 // ConferenceAvatarPermissionChanged
 	WS_CONF_SUSPEND_MEETING                          = 4229
 	WS_CONF_SUSPEND_MEETING_REQ_RESULT               = 4230
@@ -129,7 +129,7 @@ const (
 	WS_CONF_HOST_CHANGE_INDICATION                   = 7940 // ConferenceHostChangeIndication
 	WS_CONF_COHOST_CHANGE_INDICATION                 = 7941 // ConferenceCohostChangeIndication
 	WS_CONF_HOLD_CHANGE_INDICATION                   = 7942 // ConferenceHoldChangeIndication
-	WS_CONF_CLOSED_CAPTION_INDICATION                = 7943
+	WS_CONF_CLOSED_CAPTION_INDICATION                = 7943 // ConferenceClosedCaptionIndication
 	WS_CONF_CHAT_INDICATION                          = 7944 // ConferenceChatIndication
 	WS_CONF_OPTION_INDICATION                        = 7945 // ConferenceOptionIndication
 	WS_CONF_KV_UPDATE_INDICATION                     = 7946
@@ -157,12 +157,12 @@ const (
 	WS_VIDEO_LEADERSHIP_INDICATION                   = 16135
 	WS_VIDEO_SUBSCRIBE_REQ                           = 12289
 	WS_VIDEO_UNSUBSCRIBE_REQ                         = 12291
-	WS_VIDEO_KEY_FRAME_REQ                           = 12293
+	WS_VIDEO_KEY_FRAME_REQ                           = 12293 // VideoKeyFrameRequest
 	WS_VIDEO_NETWORK_FEEDBACK                        = 12295
 	WS_VIDEO_MUTE_VIDEO_REQ                          = 12297
 	WS_VIDEO_SPOTLIGHT_VIDEO_REQ                     = 12299
-	WS_SHARING_PAUSE_REQ                             = 16385
-	WS_SHARING_RESUME_REQ                            = 16387
+	WS_SHARING_PAUSE_REQ                             = 16385 // SharingPauseRequest
+	WS_SHARING_RESUME_REQ                            = 16387 // SharingResumeRequest
 	WS_SHARING_STATUS_INDICATION                     = 20225 // SharingStatusIndication
 	WS_SHARING_SIZE_CHANGE_INDICATION                = 20226
 	WS_CONF_ALLOW_ANONYMOUS_QUESTION_REQ             = 4155
@@ -10,3 +10,10 @@ func RewriteAudioHack(header []byte, payload []byte) []byte {
 	reformattedPayload = append(reformattedPayload, payload...)
 	return reformattedPayload
 }
+
+// SplitAudioHack undoes RewriteAudioHack for sending, it splits a marshalled RtpEncryptedPayload into the
+// header that goes into the audio IV rtp extension and the actual payload of the rtp packet
+func SplitAudioHack(encodedPayload []byte, headerLength int) (header []byte, payload []byte) {
+	withoutPrefix := encodedPayload[LEN_PREFIX+LEN_VERSION:]
+	return withoutPrefix[:headerLength], withoutPrefix[headerLength:]
+}
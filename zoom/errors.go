@@ -0,0 +1,14 @@
+package zoom
+
+import "errors"
+
+var (
+	ErrInvalidHandler      = errors.New("handler must be a func(*T) or func(*T) error")
+	ErrAlreadyConnected    = errors.New("session is already connected")
+	ErrNotConnected        = errors.New("session is not connected")
+	ErrMeetingEnded        = errors.New("meeting has ended")
+	ErrNotInBreakoutRoom   = errors.New("session is not in a breakout room")
+	ErrUnknownBreakoutRoom = errors.New("no breakout room with this bID")
+	ErrWrongStreamType     = errors.New("streams can not publish this kind of media")
+	ErrNoSendingSsrc       = errors.New("zoom did not assign us an ssrc to send with yet")
+)
@@ -0,0 +1,201 @@
+package zoom
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// events generated by zoomer itself rather than received over the websocket, numbered above anything zoom uses
+const (
+	LOCAL_EVT_TYPE_BASE             = 65536
+	LOCAL_SESSION_RECONNECTING      = 65537 // SessionReconnecting
+	LOCAL_SESSION_RECONNECTED       = 65538 // SessionReconnected
+	LOCAL_PARTICIPANT_JOINED        = 65539 // ParticipantJoined
+	LOCAL_PARTICIPANT_LEFT          = 65540 // ParticipantLeft
+	LOCAL_PARTICIPANT_RENAMED       = 65541 // Renamed
+	LOCAL_PARTICIPANT_HAND_RAISED   = 65542 // HandRaised
+	LOCAL_PARTICIPANT_HAND_LOWERED  = 65543 // HandLowered
+	LOCAL_PARTICIPANT_MUTE_CHANGED  = 65544 // MuteChanged
+	LOCAL_PARTICIPANT_VIDEO_CHANGED = 65545 // VideoChanged
+	LOCAL_BREAKOUT_HELP_REQUESTED   = 65546 // BreakoutHelpRequested
+	LOCAL_PARTICIPANT_HOLD_CHANGED  = 65547 // HoldChanged
+	LOCAL_PARTICIPANT_ROLE_CHANGED  = 65548 // RoleChanged
+)
+
+var localMessageNumberToName = map[int]string{
+	65536: "LOCAL_EVT_TYPE_BASE",
+	65537: "LOCAL_SESSION_RECONNECTING",
+	65538: "LOCAL_SESSION_RECONNECTED",
+	65539: "LOCAL_PARTICIPANT_JOINED",
+	65540: "LOCAL_PARTICIPANT_LEFT",
+	65541: "LOCAL_PARTICIPANT_RENAMED",
+	65542: "LOCAL_PARTICIPANT_HAND_RAISED",
+	65543: "LOCAL_PARTICIPANT_HAND_LOWERED",
+	65544: "LOCAL_PARTICIPANT_MUTE_CHANGED",
+	65545: "LOCAL_PARTICIPANT_VIDEO_CHANGED",
+	65546: "LOCAL_BREAKOUT_HELP_REQUESTED",
+	65547: "LOCAL_PARTICIPANT_HOLD_CHANGED",
+	65548: "LOCAL_PARTICIPANT_ROLE_CHANGED",
+}
+
+func init() {
+	for evt, name := range localMessageNumberToName {
+		MessageNumberToName[evt] = name
+	}
+}
+
+/*
+Subscription is returned whenever a handler is registered on a session, hold on to it if you
+want to remove the handler again later on.
+*/
+type Subscription struct {
+	id       uint64
+	evt      int
+	catchAll bool
+	handler  onMessage
+	bus      *eventBus
+}
+
+// Unsubscribe removes the handler from the session, it is safe to call this more than once
+// and from within the handler itself.
+func (subscription *Subscription) Unsubscribe() {
+	subscription.bus.remove(subscription)
+}
+
+/*
+eventBus fans out every decoded websocket message to the handlers subscribed to its evt number.
+
+Handlers are called synchronously from the websocket read loop in the order in which they were
+registered, handlers for a specific evt run before the catch-all handlers. An error returned by
+a handler is logged and does not stop the remaining handlers from running.
+*/
+type eventBus struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	handlers map[ /*evt*/ int][]*Subscription
+	catchAll []*Subscription
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		handlers: make(map[int][]*Subscription),
+	}
+}
+
+func (bus *eventBus) add(evt int, catchAll bool, handler onMessage) *Subscription {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.nextID++
+	subscription := &Subscription{
+		id:       bus.nextID,
+		evt:      evt,
+		catchAll: catchAll,
+		handler:  handler,
+		bus:      bus,
+	}
+	if catchAll {
+		bus.catchAll = append(bus.catchAll, subscription)
+	} else {
+		bus.handlers[evt] = append(bus.handlers[evt], subscription)
+	}
+	return subscription
+}
+
+func (bus *eventBus) remove(subscription *Subscription) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	if subscription.catchAll {
+		bus.catchAll = removeSubscription(bus.catchAll, subscription)
+		return
+	}
+
+	bus.handlers[subscription.evt] = removeSubscription(bus.handlers[subscription.evt], subscription)
+	if len(bus.handlers[subscription.evt]) == 0 {
+		delete(bus.handlers, subscription.evt)
+	}
+}
+
+// removeSubscription copies the slice so that a dispatch which is currently iterating over the
+// old slice is not affected by handlers unsubscribing themselves.
+func removeSubscription(subscriptions []*Subscription, needle *Subscription) []*Subscription {
+	remaining := make([]*Subscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		if subscription.id != needle.id {
+			remaining = append(remaining, subscription)
+		}
+	}
+	return remaining
+}
+
+func (bus *eventBus) dispatch(session *ZoomSession, evt int, message Message) {
+	bus.mu.RLock()
+	subscriptions := make([]*Subscription, 0, len(bus.handlers[evt])+len(bus.catchAll))
+	subscriptions = append(subscriptions, bus.handlers[evt]...)
+	subscriptions = append(subscriptions, bus.catchAll...)
+	bus.mu.RUnlock()
+
+	for _, subscription := range subscriptions {
+		err := subscription.handler(session, message)
+		if err != nil {
+			log.Printf("Handler for %s (%d) failed: %+v", MessageNumberToName[evt], evt, err)
+		}
+	}
+}
+
+/*
+On registers a handler for a single evt number, the handler receives the already decoded message body:
+
+	session.On(zoom.WS_CONF_CHAT_INDICATION, func(m *zoom.ConferenceChatIndication) error {
+		...
+	})
+
+The handler has to be a func(*T) or func(*T) error where T is the type registered for the evt in
+zoom/message.go, a mismatch is reported when subscribing rather than when the message arrives.
+*/
+func (session *ZoomSession) On(eventNumber int, handler interface{}) (*Subscription, error) {
+	typ := msgTypes[eventNumber]
+	if typ == nil {
+		return nil, fmt.Errorf("no type definition for %s (%d) in zoom/message.go", MessageNumberToName[eventNumber], eventNumber)
+	}
+
+	if handler == nil {
+		return nil, ErrInvalidHandler
+	}
+	fn := reflect.ValueOf(handler)
+	fnType := fn.Type()
+	if fnType.Kind() != reflect.Func || fnType.NumIn() != 1 || fnType.NumOut() > 1 {
+		return nil, ErrInvalidHandler
+	}
+	if fnType.NumOut() == 1 && fnType.Out(0) != errorType {
+		return nil, ErrInvalidHandler
+	}
+	if fnType.In(0) != reflect.PtrTo(typ) {
+		return nil, fmt.Errorf("handler for %s (%d) must accept %v, not %v", MessageNumberToName[eventNumber], eventNumber, reflect.PtrTo(typ), fnType.In(0))
+	}
+
+	wrapped := func(session *ZoomSession, message Message) error {
+		out := fn.Call([]reflect.Value{reflect.ValueOf(message)})
+		if len(out) == 1 && !out[0].IsNil() {
+			return out[0].Interface().(error)
+		}
+		return nil
+	}
+	return session.events.add(eventNumber, false, wrapped), nil
+}
+
+// emit dispatches an event generated by zoomer itself to the subscribed handlers
+func (session *ZoomSession) emit(eventNumber int, message Message) {
+	session.events.dispatch(session, eventNumber, message)
+}
+
+// OnMessage registers a handler that receives every decoded message, this is the same as the
+// callback passed to MakeWebsocketConnection.
+func (session *ZoomSession) OnMessage(handler onMessage) *Subscription {
+	return session.events.add(0, true, handler)
+}
@@ -0,0 +1,92 @@
+package zoom
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEventBusOrdering(t *testing.T) {
+	session := &ZoomSession{events: newEventBus()}
+
+	calls := make([]string, 0)
+	session.OnMessage(func(session *ZoomSession, message Message) error {
+		calls = append(calls, "catchAll")
+		return nil
+	})
+	_, err := session.On(WS_CONF_CHAT_INDICATION, func(m *ConferenceChatIndication) {
+		calls = append(calls, "first:"+string(m.Text))
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = session.On(WS_CONF_CHAT_INDICATION, func(m *ConferenceChatIndication) error {
+		calls = append(calls, "second:"+string(m.Text))
+		return errors.New("failing handlers should not stop the others")
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	session.events.dispatch(session, WS_CONF_CHAT_INDICATION, &ConferenceChatIndication{Text: []byte("hi")})
+
+	expected := []string{"first:hi", "second:hi", "catchAll"}
+	if len(calls) != len(expected) {
+		t.Errorf("expected %v calls, got %v", expected, calls)
+		return
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("expected %v calls, got %v", expected, calls)
+			return
+		}
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	session := &ZoomSession{events: newEventBus()}
+
+	count := 0
+	var subscription *Subscription
+	subscription, err := session.On(WS_CONF_HOLD_CHANGE_INDICATION, func(m *ConferenceHoldChangeIndication) {
+		count++
+		subscription.Unsubscribe()
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	session.events.dispatch(session, WS_CONF_HOLD_CHANGE_INDICATION, &ConferenceHoldChangeIndication{})
+	session.events.dispatch(session, WS_CONF_HOLD_CHANGE_INDICATION, &ConferenceHoldChangeIndication{})
+	subscription.Unsubscribe()
+
+	if count != 1 {
+		t.Errorf("expected handler to be called once, was called %v times", count)
+	}
+}
+
+func TestEventBusInvalidHandler(t *testing.T) {
+	session := &ZoomSession{events: newEventBus()}
+
+	invalidHandlers := []interface{}{
+		nil,
+		"not a function",
+		func() {},
+		func(m *ConferenceChatIndication) int { return 0 },
+		func(m *ConferenceHoldChangeIndication) {},
+		func(m ConferenceChatIndication) {},
+	}
+	for _, handler := range invalidHandlers {
+		_, err := session.On(WS_CONF_CHAT_INDICATION, handler)
+		if err == nil {
+			t.Errorf("expected handler %T to be rejected", handler)
+		}
+	}
+
+	_, err := session.On(WS_CONF_KV_UPDATE_INDICATION, func(m *ConferenceChatIndication) {})
+	if err == nil {
+		t.Error("expected evt without type definition to be rejected")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -119,7 +120,7 @@ func (session *ZoomSession) GetMeetingInfoData() (*MeetingInfo, string, error) {
 	}
 
 	if meetingInfo.ErrorCode > 0 {
-		return nil, "", fmt.Errorf(meetingInfo.ErrorMessage)
+		return nil, "", errors.New(meetingInfo.ErrorMessage)
 	}
 
 	var cookieString string
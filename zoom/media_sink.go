@@ -0,0 +1,107 @@
+package zoom
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/opus"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+)
+
+// MediaSample is a single decrypted sample from one of the media streams
+type MediaSample struct {
+	SSRC       uint32
+	UserID     int
+	StreamType rtp.StreamType
+	// rtp timestamp of the sample
+	Timestamp uint32
+	// whether a decoder can start at this sample, always true for audio
+	KeyFrame bool
+	// annex B H.264 for video and screenshare, a single opus packet for audio
+	Data []byte
+}
+
+/*
+MediaSink receives everything that comes in on a ZoomStreams.
+
+Audio sinks that also implement opus.AudioSink get the decoded PCM of every participant on top of the
+opus packets. The callbacks are called from the goroutine reading the media websocket, so don't block in them.
+*/
+type MediaSink interface {
+	OnSample(sample *MediaSample)
+	// broken packets are reported here and skipped, the streams only stop when the websocket fails
+	OnError(err error)
+}
+
+/*
+FileSink is the MediaSink used when none is passed to the Create*Streams functions, it writes the video
+and screenshare of every participant into timestamped .h264 files and their audio into .raw files.
+*/
+type FileSink struct {
+	mu    sync.Mutex
+	video *ParticipantVideoSink
+	audio *opus.PCMRecorder
+}
+
+func NewFileSink() *FileSink {
+	prefix := time.Now().Format("2006-01-02-15-04-05")
+	return &FileSink{
+		video: NewParticipantVideoSink(func(userID int, ssrc uint32, segment int) (io.WriteCloser, error) {
+			return os.Create(fmt.Sprintf("%s-%d-%d.h264", prefix, userID, segment))
+		}),
+	}
+}
+
+func (sink *FileSink) OnSample(sample *MediaSample) {
+	// audio is recorded decoded, see OnPCM
+	sink.video.OnSample(sample)
+}
+
+func (sink *FileSink) OnPCM(userID int, samples []int16, timestamp uint32) {
+	sink.mu.Lock()
+	if sink.audio == nil {
+		sink.audio, _ = opus.CreateNewPCMRecorder()
+	}
+	audio := sink.audio
+	sink.mu.Unlock()
+
+	audio.OnPCM(userID, samples, timestamp)
+}
+
+func (sink *FileSink) OnError(err error) {
+	log.Printf("Media error: %+v", err)
+}
+
+func (sink *FileSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	err := sink.video.Close()
+	if sink.audio != nil {
+		audioErr := sink.audio.Close()
+		if err == nil {
+			err = audioErr
+		}
+		sink.audio = nil
+	}
+	return err
+}
+
+// PCMSink is a MediaSink for audio streams that only passes on the decoded audio, e.g. to an opus.AudioMixer
+type PCMSink struct {
+	opus.AudioSink
+}
+
+func NewPCMSink(audioSink opus.AudioSink) *PCMSink {
+	return &PCMSink{AudioSink: audioSink}
+}
+
+func (sink *PCMSink) OnSample(sample *MediaSample) {}
+
+func (sink *PCMSink) OnError(err error) {
+	log.Printf("Media error: %+v", err)
+}
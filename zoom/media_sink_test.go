@@ -0,0 +1,123 @@
+package zoom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+	"github.com/gorilla/websocket"
+)
+
+type errorSink struct {
+	errors chan error
+}
+
+func (sink *errorSink) OnSample(sample *MediaSample) {}
+
+func (sink *errorSink) OnError(err error) {
+	sink.errors <- err
+}
+
+func TestBrokenPacketsAreReportedToSink(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		// truncated audio packet followed by a truncated video packet
+		connection.WriteMessage(websocket.BinaryMessage, []byte{RTP_AUDIO_PKT, 0x00})
+		connection.WriteMessage(websocket.BinaryMessage, []byte{RTP_VIDEO_PKT, 0x00})
+		connection.ReadMessage()
+	}))
+	defer server.Close()
+
+	connection, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer connection.Close()
+
+	sink := &errorSink{errors: make(chan error, 3)}
+	streams := &ZoomStreams{
+		recv:       connection,
+		decoder:    rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_AUDIO),
+		streamType: rtp.STREAM_TYPE_AUDIO,
+		sink:       sink,
+	}
+	go streams.StartReceiveChannel()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-sink.errors:
+			if err == nil {
+				t.Error("expected an error for a broken packet")
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("expected 2 errors, got %v", i)
+			return
+		}
+	}
+}
+
+func TestReplacedConnectionIsNotAnError(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		connection.ReadMessage()
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	old, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	current, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer current.Close()
+
+	sink := &errorSink{errors: make(chan error, 1)}
+	streams := &ZoomStreams{
+		recv:       old,
+		decoder:    rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_AUDIO),
+		streamType: rtp.STREAM_TYPE_AUDIO,
+		sink:       sink,
+	}
+	done := make(chan struct{})
+	go func() {
+		streams.receive(old)
+		close(done)
+	}()
+
+	// what switchMeeting does once the new connection is up
+	streams.mu.Lock()
+	streams.recv = current
+	streams.mu.Unlock()
+	old.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("expected the receiver of the old connection to stop")
+		return
+	}
+	select {
+	case err := <-sink.errors:
+		t.Errorf("expected no error for a connection we replaced, got %v", err)
+	default:
+	}
+}
@@ -5,8 +5,6 @@ import (
 	"fmt"
 	"log"
 	"reflect"
-
-	"github.com/gorilla/websocket"
 )
 
 var msgTypes = map[int]reflect.Type{
@@ -21,6 +19,7 @@ var msgTypes = map[int]reflect.Type{
 	WS_AUDIO_VOIP_JOIN_CHANNEL_REQ: reflect.TypeOf(AudioVoipJoinChannelRequest{}),
 	// sender implemented, working
 	WS_AUDIO_MUTE_REQ: reflect.TypeOf(AudioMuteRequest{}),
+	WS_AUDIO_MUTE_RES: reflect.TypeOf(AudioMuteResponse{}),
 	// sender implemented, working
 	WS_CONF_SET_SHARE_STATUS_REQ: reflect.TypeOf(ConferenceSetShareStatusRequest{}),
 	// sender implemented, working
@@ -46,6 +45,7 @@ var msgTypes = map[int]reflect.Type{
 	WS_CONF_DC_REGION_INDICATION:              reflect.TypeOf(ConferenceDCRegionIndication{}),
 	WS_AUDIO_SSRC_INDICATION:                  reflect.TypeOf(SSRCIndication{}),
 	WS_VIDEO_SSRC_INDICATION:                  reflect.TypeOf(SSRCIndication{}),
+	WS_VIDEO_KEY_FRAME_REQ:                    reflect.TypeOf(VideoKeyFrameRequest{}),
 	WS_VIDEO_ACTIVE_INDICATION:                reflect.TypeOf(VideoActiveIndication{}),
 	WS_SHARING_STATUS_INDICATION:              reflect.TypeOf(SharingStatusIndication{}),
 	WS_SHARING_ASSIGNED_SENDING_SSRC:          reflect.TypeOf(SharingAssignedSendingSsrcResponse{}),
@@ -67,12 +67,77 @@ var msgTypes = map[int]reflect.Type{
 	WS_CONF_BO_JOIN_REQ: reflect.TypeOf(ConferenceBreakoutRoomJoinRequest{}),
 	WS_CONF_BO_JOIN_RES: reflect.TypeOf(ConferenceBreakoutRoomJoinResponse{}),
 	// sender implemented, untested
+	WS_CONF_BO_LEAVE_REQ: reflect.TypeOf(ConferenceBreakoutRoomLeaveRequest{}),
+	// sender implemented, untested
+	WS_CONF_BO_STOP_REQ: reflect.TypeOf(ConferenceBreakoutRoomStopRequest{}),
+	// sender implemented, untested
+	WS_CONF_BO_ASSIGN_REQ: reflect.TypeOf(ConferenceBreakoutRoomAssignRequest{}),
+	// sender implemented, untested
+	WS_CONF_BO_SWITCH_REQ: reflect.TypeOf(ConferenceBreakoutRoomSwitchRequest{}),
+	// sender implemented, untested
+	WS_CONF_BO_PRE_ASSIGN_REQ: reflect.TypeOf(ConferenceBreakoutRoomPreAssignRequest{}),
+	WS_CONF_BO_PRE_ASSIGN_RES: reflect.TypeOf(ConferenceBreakoutRoomPreAssignResponse{}),
+	// sender implemented, untested
+	WS_CONF_BO_HELP_REQ: reflect.TypeOf(ConferenceBreakoutRoomHelpRequest{}),
+	// sender implemented, untested
 	WS_CONF_END_REQ: reflect.TypeOf(ConferenceEndRequest{}),
+	WS_CONF_END_RES: reflect.TypeOf(ConferenceEndResponse{}),
+	// sender implemented, untested
+	WS_CONF_LEAVE_REQ: reflect.TypeOf(ConferenceLeaveRequest{}),
 	// sender implemented, doesn't work???
 	WS_CONF_BO_TOKEN_BATCH_REQ:     reflect.TypeOf(ConferenceBreakoutRoomTokenBatchRequest{}),
 	WS_CONF_BO_TOKEN_RES:           reflect.TypeOf(ConferenceBreakoutRoomTokenResponse{}),
 	WS_CONF_HOST_CHANGE_INDICATION: reflect.TypeOf(ConferenceHostChangeIndication{}),
 	WS_CONF_END_INDICATION:         reflect.TypeOf(ConferenceEndIndication{}),
+	WS_CONF_HOLD_CHANGE_INDICATION: reflect.TypeOf(ConferenceHoldChangeIndication{}),
+	// sender implemented, untested
+	WS_SHARING_PAUSE_REQ: reflect.TypeOf(SharingPauseRequest{}),
+	// sender implemented, untested
+	WS_SHARING_RESUME_REQ: reflect.TypeOf(SharingResumeRequest{}),
+	// sender implemented, untested
+	WS_CONF_PUT_ON_HOLD_REQ: reflect.TypeOf(ConferencePutOnHoldRequest{}),
+	// sender implemented, untested
+	WS_CONF_SET_HOLD_UPON_ENTRY_REQ: reflect.TypeOf(ConferenceSetHoldUponEntryRequest{}),
+	// sender implemented, untested
+	WS_CONF_ADMIT_ALL_SILENT_USERS_REQ: reflect.TypeOf(ConferenceAdmitAllSilentUsersRequest{}),
+	// sender implemented, untested
+	WS_CONF_EXPEL_REQ: reflect.TypeOf(ConferenceExpelRequest{}),
+	WS_CONF_EXPEL_RES: reflect.TypeOf(ConferenceExpelResponse{}),
+	// sender implemented, untested
+	WS_CONF_EXPEL_ATTENDEE_REQ: reflect.TypeOf(ConferenceExpelAttendeeRequest{}),
+	WS_CONF_EXPEL_ATTENDEE_RES: reflect.TypeOf(ConferenceExpelAttendeeResponse{}),
+	// sender implemented, untested
+	WS_CONF_LOCK_REQ: reflect.TypeOf(ConferenceLockRequest{}),
+	WS_CONF_LOCK_RES: reflect.TypeOf(ConferenceLockResponse{}),
+	// sender implemented, untested
+	WS_CONF_HOST_KEY_REQ: reflect.TypeOf(ConferenceHostKeyRequest{}),
+	WS_CONF_HOST_KEY_RES: reflect.TypeOf(ConferenceHostKeyResponse{}),
+	// sender implemented, untested
+	WS_CONF_RECLAIM_HOST_REQ: reflect.TypeOf(ConferenceReclaimHostRequest{}),
+	// sender implemented, untested
+	WS_CONF_ASSIGN_HOST_REQ: reflect.TypeOf(ConferenceAssignHostRequest{}),
+	// sender implemented, untested
+	WS_CONF_REVOKE_COHOST_REQ: reflect.TypeOf(ConferenceRevokeCoHostRequest{}),
+	// sender implemented, untested
+	WS_CONF_ASSIGN_CC_REQ: reflect.TypeOf(ConferenceAssignCaptionerRequest{}),
+	// sender implemented, untested
+	WS_CONF_CLOSED_CAPTION_REQ:        reflect.TypeOf(ConferenceClosedCaptionRequest{}),
+	WS_CONF_CLOSED_CAPTION_RES:        reflect.TypeOf(ConferenceClosedCaptionResponse{}),
+	WS_CONF_CLOSED_CAPTION_INDICATION: reflect.TypeOf(ConferenceClosedCaptionIndication{}),
+
+	// zoomer events, see events.go
+	LOCAL_SESSION_RECONNECTING:      reflect.TypeOf(SessionReconnecting{}),
+	LOCAL_SESSION_RECONNECTED:       reflect.TypeOf(SessionReconnected{}),
+	LOCAL_PARTICIPANT_JOINED:        reflect.TypeOf(ParticipantJoined{}),
+	LOCAL_PARTICIPANT_LEFT:          reflect.TypeOf(ParticipantLeft{}),
+	LOCAL_PARTICIPANT_RENAMED:       reflect.TypeOf(Renamed{}),
+	LOCAL_PARTICIPANT_HAND_RAISED:   reflect.TypeOf(HandRaised{}),
+	LOCAL_PARTICIPANT_HAND_LOWERED:  reflect.TypeOf(HandLowered{}),
+	LOCAL_PARTICIPANT_MUTE_CHANGED:  reflect.TypeOf(MuteChanged{}),
+	LOCAL_PARTICIPANT_VIDEO_CHANGED: reflect.TypeOf(VideoChanged{}),
+	LOCAL_BREAKOUT_HELP_REQUESTED:   reflect.TypeOf(BreakoutHelpRequested{}),
+	LOCAL_PARTICIPANT_HOLD_CHANGED:  reflect.TypeOf(HoldChanged{}),
+	LOCAL_PARTICIPANT_ROLE_CHANGED:  reflect.TypeOf(RoleChanged{}),
 }
 
 func GetMessageBody(message *GenericZoomMessage) (interface{}, error) {
@@ -88,10 +153,16 @@ func GetMessageBody(message *GenericZoomMessage) (interface{}, error) {
 	return p, nil
 }
 
-func (session *ZoomSession) SendMessage(connection *websocket.Conn, eventNumber int, body interface{}) error {
+// SendMessage sends a message over the current connection, ErrNotConnected while there is none (eg. while reconnecting or after Leave)
+func (session *ZoomSession) SendMessage(eventNumber int, body interface{}) error {
 	session.mu.Lock() // gorilla/websocket only allows for 1 sender at a time + the send sequence number shouldn't be written to simultaneously
 	defer session.mu.Unlock()
 
+	connection := session.websocketConnection
+	if connection == nil {
+		return ErrNotConnected
+	}
+
 	session.sendSequenceNumber++
 
 	message := GenericZoomMessage{
@@ -68,20 +68,31 @@ type ConferenceRosterIndication struct {
 		Role               int                  `json:"role,omitempty"`
 		Type               int                  `json:"type,omitempty"`
 		ZoomID             string               `json:"zoomID,omitempty"`
+		// only sent for people that are signed in, and probably only to the host (untested)
+		Email string `json:"email,omitempty"`
+		// not always sent along, nil means unknown
+		Muted                 *bool `json:"muted,omitempty"`
+		BVideoOn              *bool `json:"bVideoOn,omitempty"`
+		BCoHost               *bool `json:"bCoHost,omitempty"`
+		AudioConnectionStatus *int  `json:"audioConnectionStatus,omitempty"`
 	} `json:"add"`
 	Update []struct {
-		// all these fields are optional
+		// all these fields are optional, the pointers are nil when they did not change
 		Caps                  int                  `json:"caps,omitempty"`
 		Dn2                   BytesBase64NoPadding `json:"dn2,omitempty"` // renames
 		ID                    int                  `json:"id,omitempty"`
-		Muted                 bool                 `json:"muted,omitempty"`
-		BVideoOn              bool                 `json:"bVideoOn,omitempty"`
+		Muted                 *bool                `json:"muted,omitempty"`
+		BVideoOn              *bool                `json:"bVideoOn,omitempty"`
 		Audio                 string               `json:"audio,omitempty"`
-		AudioConnectionStatus int                  `json:"audioConnectionStatus,omitempty"`
+		AudioConnectionStatus *int                 `json:"audioConnectionStatus,omitempty"`
 		BAudioUnencrypted     bool                 `json:"bAudioUnencrytped,omitempty"`
-		BCoHost               bool                 `json:"bCoHost,omitempty"`
+		BCoHost               *bool                `json:"bCoHost,omitempty"`
 		BRaiseHand            *bool                `json:"bRaiseHand,omitempty"`
-		Role                  int                  `json:"role,omitempty"`
+		Role                  *int                 `json:"role,omitempty"`
+		// someone was admitted from or sent back to the waiting room
+		BHold *bool `json:"bHold,omitempty"`
+		// someone was allowed or no longer allowed to type closed captions
+		BCCEditor *bool `json:"bCCEditor,omitempty"`
 	} `json:"update"`
 	Remove []struct {
 		ID          int `json:"id,omitempty"`
@@ -150,6 +161,74 @@ type ConferenceLockShareRequest struct {
 
 type ConferenceEndRequest struct{}
 
+// most responses only carry a result code, 0 means success
+type ResultResponse struct {
+	Res int `json:"res"`
+}
+
+type ConferenceEndResponse ResultResponse
+type AudioMuteResponse ResultResponse
+
+// the bodies of expel and lock are a guess
+type ConferenceExpelRequest struct {
+	ID int `json:"id"`
+}
+
+// webinar attendees are expelled with a request of their own
+type ConferenceExpelAttendeeRequest ConferenceExpelRequest
+
+type ConferenceLockRequest struct {
+	BLock bool `json:"bLock"`
+}
+
+// host and co-host, the bodies are a guess as well
+type ConferenceHostKeyRequest struct {
+	HostKey string `json:"hostKey"`
+}
+
+type ConferenceReclaimHostRequest struct{}
+
+// co-host when BCoHost is set, host otherwise
+type ConferenceAssignHostRequest struct {
+	ID      int  `json:"id"`
+	BCoHost bool `json:"bCoHost,omitempty"`
+}
+
+type ConferenceRevokeCoHostRequest struct {
+	ID int `json:"id"`
+}
+
+// closed captions, the bodies are a guess
+type ConferenceAssignCaptionerRequest struct {
+	ID        int  `json:"id"`
+	BCCEditor bool `json:"bCCEditor"`
+}
+
+type ConferenceClosedCaptionRequest struct {
+	Text string `json:"text"`
+	// a language tag like "en-US"
+	Lang string `json:"lang,omitempty"`
+	// counts up with every caption we send, zoom shows them in this order
+	Seq int `json:"seq"`
+}
+
+type ConferenceClosedCaptionIndication struct {
+	MsgID  string `json:"msgId"`
+	UserID int    `json:"userId"`
+	Text   string `json:"text"`
+	Lang   string `json:"lang"`
+	Seq    int    `json:"seq"`
+	Time   int64  `json:"time"`
+}
+
+type ConferenceExpelResponse ResultResponse
+type ConferenceExpelAttendeeResponse ResultResponse
+type ConferenceLockResponse ResultResponse
+type ConferenceHostKeyResponse ResultResponse
+type ConferenceClosedCaptionResponse ResultResponse
+
+type ConferenceLeaveRequest struct{}
+
 type ConferenceLocalRecordIndication struct{}
 
 type ConferenceOptionIndication struct {
@@ -170,6 +249,11 @@ type SSRCIndication struct {
 	SSRC int `json:"ssrc"`
 }
 
+// sent when someone can't decode our video anymore and by us when we can't decode theirs (untested)
+type VideoKeyFrameRequest struct {
+	SSRC int `json:"ssrc"`
+}
+
 type VideoActiveIndication struct {
 	BVideoOn bool `json:"bVideoOn"`
 	ID       int  `json:"id"`
@@ -216,6 +300,43 @@ type ConferenceBreakoutRoomJoinResponse struct {
 	ConfID  string `json:"confID"`
 }
 
+type ConferenceBreakoutRoomLeaveRequest struct{}
+
+type ConferenceBreakoutRoomStopRequest struct{}
+
+// assigning and switching use the same body, assign is for people who are not in a room yet
+type ConferenceBreakoutRoomAssignRequest struct {
+	TargetID  int    `json:"targetID"`
+	TargetBID string `json:"targetBID"`
+}
+
+type ConferenceBreakoutRoomSwitchRequest ConferenceBreakoutRoomAssignRequest
+
+// pre-assigning is done before the rooms are started and uses the zoom IDs because user IDs change when people rejoin
+type ConferenceBreakoutRoomPreAssignRequest struct {
+	TargetBID       string   `json:"targetBID"`
+	ParticipantList []string `json:"participantList"`
+}
+
+type ConferenceBreakoutRoomPreAssignResponse struct {
+	Res int `json:"res"`
+}
+
+// sent from within a breakout room to ask the host for help
+type ConferenceBreakoutRoomHelpRequest struct {
+	Bid string `json:"bid"`
+}
+
+// ConferenceBreakoutRoomCommandIndication.CommandType of a help request forwarded to the host, untested
+const BO_COMMAND_TYPE_HELP = "help"
+
+// values for ConferenceBreakoutRoomAttributeIndicationData.ControlStatus
+const (
+	BO_CONTROL_STATUS_NOT_STARTED = 1
+	BO_CONTROL_STATUS_STARTED     = 2
+	BO_CONTROL_STATUS_STOPPING    = 3
+)
+
 type ConferenceBreakoutRoomCommandIndication struct {
 	// although this could be represented as bytesbase64nopadding data there is no point in doing so because we are not going to be anything other than copying it verbatim
 	Botoken     string               `json:"botoken,omitempty"`
@@ -242,7 +363,7 @@ type ConferenceBreakoutRoomAttributeIndicationData struct {
 	TimerDuration              int                `json:"TimerDuration"`
 	IsTimerAutoEndEnabled      bool               `json:"IsTimerAutoEndEnabled"`
 	WaitSeconds                int                `json:"WaitSeconds"`
-	StartTimeOnMMR             interface{}        `json:"StartTimeOnMMR"`
+	StartTimeOnMMR             interface{}        `json:"StartTimeOnMMR,omitempty"`
 	ItemList                   []BreakoutRoomItem `json:"ItemList"`
 	// ItemList                   []struct {
 	// 	BID             string   `json:"BID"`
@@ -277,6 +398,25 @@ type SharingSubscribeRequest struct {
 	Size int `json:"size"`
 }
 
+// the bodies of pause and resume are a guess
+type SharingPauseRequest struct {
+	ID int `json:"id"`
+}
+
+type SharingResumeRequest struct {
+	ID int `json:"id"`
+}
+
+// waiting room, the bodies are a guess
+type ConferencePutOnHoldRequest struct {
+	BHold bool `json:"bHold"`
+	ID    int  `json:"id"`
+}
+
+type ConferenceSetHoldUponEntryRequest BOnRequest
+
+type ConferenceAdmitAllSilentUsersRequest struct{}
+
 type SharingAssignedSendingSsrcResponse struct {
 	SSRC int `json:"ssrc"`
 }
@@ -0,0 +1,150 @@
+package zoom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// responses that do not follow the usual REQ = n, RES = n + 1 numbering
+var responseTypes = map[ /*request evt*/ int] /*response evt*/ int{
+	WS_CONF_BO_TOKEN_BATCH_REQ: WS_CONF_BO_TOKEN_RES,
+}
+
+// ResponseEventNumber returns the evt of the response zoom sends for a request, if there is one
+func ResponseEventNumber(requestEvt int) (int, bool) {
+	if responseEvt, ok := responseTypes[requestEvt]; ok {
+		return responseEvt, true
+	}
+	if strings.HasSuffix(MessageNumberToName[requestEvt], "_REQ") && strings.HasSuffix(MessageNumberToName[requestEvt+1], "_RES") {
+		return requestEvt + 1, true
+	}
+	return 0, false
+}
+
+// ResponseError is returned by Request when zoom answered with a non-zero result code
+type ResponseError struct {
+	Evt int
+	Res int
+}
+
+func (err *ResponseError) Error() string {
+	return fmt.Sprintf("%s failed with result code %d", MessageNumberToName[err.Evt], err.Res)
+}
+
+/*
+pendingRequests keeps track of who is waiting for which response.
+
+The seq zoom sends back has nothing to do with ours, so the only thing we can match on is the evt of the
+response. Zoom answers requests of the same type in the order it received them, so waiters for the same
+evt are served first come first served.
+*/
+type pendingRequests struct {
+	mu      sync.Mutex
+	waiting map[ /*response evt*/ int][]chan *GenericZoomMessage
+}
+
+func newPendingRequests() *pendingRequests {
+	return &pendingRequests{
+		waiting: make(map[int][]chan *GenericZoomMessage),
+	}
+}
+
+func (requests *pendingRequests) add(responseEvt int) chan *GenericZoomMessage {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	response := make(chan *GenericZoomMessage, 1)
+	requests.waiting[responseEvt] = append(requests.waiting[responseEvt], response)
+	return response
+}
+
+func (requests *pendingRequests) remove(responseEvt int, response chan *GenericZoomMessage) {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	waiting := requests.waiting[responseEvt]
+	for i, candidate := range waiting {
+		if candidate == response {
+			requests.waiting[responseEvt] = append(waiting[:i:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(requests.waiting[responseEvt]) == 0 {
+		delete(requests.waiting, responseEvt)
+	}
+}
+
+// resolve hands the message to the oldest request waiting for it, it reports whether anyone was waiting
+func (requests *pendingRequests) resolve(message *GenericZoomMessage) bool {
+	requests.mu.Lock()
+	defer requests.mu.Unlock()
+
+	waiting := requests.waiting[message.Evt]
+	if len(waiting) == 0 {
+		return false
+	}
+	waiting[0] <- message
+	requests.waiting[message.Evt] = waiting[1:]
+	if len(requests.waiting[message.Evt]) == 0 {
+		delete(requests.waiting, message.Evt)
+	}
+	return true
+}
+
+/*
+Request sends a message and waits for zoom to respond to it, use the context for timeouts.
+
+The response is decoded like any other message, responses without a type definition in zoom/message.go
+are returned as *GenericZoomMessage. A *ResponseError is returned if the response carries a non-zero
+result code. The response is also passed on to the regular handlers.
+
+Responses are read by the same goroutine that runs the handlers, so calling Request from within a handler
+blocks until the context expires. Start a goroutine if you have to send a request from a handler.
+*/
+func (session *ZoomSession) Request(ctx context.Context, eventNumber int, body interface{}) (Message, error) {
+	responseEvt, ok := ResponseEventNumber(eventNumber)
+	if !ok {
+		return nil, fmt.Errorf("%s (%d) has no known response", MessageNumberToName[eventNumber], eventNumber)
+	}
+
+	// register before sending so we can't miss a quick response
+	response := session.requests.add(responseEvt)
+	defer session.requests.remove(responseEvt, response)
+
+	err := session.SendMessage(eventNumber, body)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case message := <-response:
+		return decodeResponse(message)
+	case <-session.done:
+		return nil, ErrNotConnected
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func decodeResponse(message *GenericZoomMessage) (Message, error) {
+	result := struct {
+		Res *int `json:"res"`
+	}{}
+	if len(message.Body) > 0 {
+		err := json.Unmarshal(message.Body, &result)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse body JSON: %+v", err)
+		}
+	}
+	if result.Res != nil && *result.Res != 0 {
+		return nil, &ResponseError{Evt: message.Evt, Res: *result.Res}
+	}
+
+	if msgTypes[message.Evt] == nil {
+		return message, nil
+	}
+	return GetMessageBody(message)
+}
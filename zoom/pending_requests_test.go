@@ -0,0 +1,140 @@
+package zoom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestResponseEventNumber(t *testing.T) {
+	cases := map[int]int{
+		WS_CONF_END_REQ:            WS_CONF_END_RES,
+		WS_AUDIO_MUTE_REQ:          WS_AUDIO_MUTE_RES,
+		WS_CONF_BO_JOIN_REQ:        WS_CONF_BO_JOIN_RES,
+		WS_CONF_BO_TOKEN_BATCH_REQ: WS_CONF_BO_TOKEN_RES,
+		WS_CONF_EXPEL_REQ:          WS_CONF_EXPEL_RES,
+		WS_CONF_LOCK_REQ:           WS_CONF_LOCK_RES,
+	}
+	for requestEvt, expectedEvt := range cases {
+		responseEvt, ok := ResponseEventNumber(requestEvt)
+		if !ok || responseEvt != expectedEvt {
+			t.Errorf("expected %v for %v, got %v", MessageNumberToName[expectedEvt], MessageNumberToName[requestEvt], MessageNumberToName[responseEvt])
+		}
+	}
+
+	_, ok := ResponseEventNumber(WS_CONF_CHAT_REQ)
+	if ok {
+		t.Error("did not expect a response for WS_CONF_CHAT_REQ")
+	}
+}
+
+func TestPendingRequestsFirstComeFirstServed(t *testing.T) {
+	requests := newPendingRequests()
+	first := requests.add(WS_CONF_END_RES)
+	second := requests.add(WS_CONF_END_RES)
+
+	if requests.resolve(&GenericZoomMessage{Evt: WS_AUDIO_MUTE_RES}) {
+		t.Error("nobody should be waiting for WS_AUDIO_MUTE_RES")
+	}
+
+	requests.resolve(&GenericZoomMessage{Evt: WS_CONF_END_RES, Seq: 1})
+	requests.resolve(&GenericZoomMessage{Evt: WS_CONF_END_RES, Seq: 2})
+
+	if message := <-first; message.Seq != 1 {
+		t.Errorf("expected first waiter to receive the first response, got seq %v", message.Seq)
+	}
+	if message := <-second; message.Seq != 2 {
+		t.Errorf("expected second waiter to receive the second response, got seq %v", message.Seq)
+	}
+	if len(requests.waiting) != 0 {
+		t.Error("expected no waiters to be left")
+	}
+}
+
+func TestDecodeResponseError(t *testing.T) {
+	_, err := decodeResponse(&GenericZoomMessage{Evt: WS_CONF_END_RES, Body: []byte(`{"res":3}`)})
+	responseErr, ok := err.(*ResponseError)
+	if !ok || responseErr.Res != 3 {
+		t.Errorf("expected a ResponseError with result code 3, got %v", err)
+	}
+
+	response, err := decodeResponse(&GenericZoomMessage{Evt: WS_CONF_END_RES, Body: []byte(`{"res":0}`)})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, ok := response.(*ConferenceEndResponse); !ok {
+		t.Errorf("expected *ConferenceEndResponse, got %T", response)
+	}
+}
+
+func TestRequest(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		for {
+			request := &GenericZoomMessage{}
+			err := connection.ReadJSON(request)
+			if err != nil {
+				return
+			}
+			if request.Evt == WS_CONF_BO_JOIN_REQ {
+				connection.WriteJSON(&GenericZoomMessage{
+					Evt:  WS_CONF_BO_JOIN_RES,
+					Seq:  42,
+					Body: []byte(`{"bid":"bid","botoken":"token","confID":"conf"}`),
+				})
+			}
+		}
+	}))
+	defer server.Close()
+
+	connection, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer connection.Close()
+
+	session := &ZoomSession{
+		events:              newEventBus(),
+		requests:            newPendingRequests(),
+		websocketConnection: connection,
+	}
+	go session.readLoop(connection, func() {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	response, err := session.RequestBreakoutRoomJoinToken(ctx, "bid")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if response.Botoken != "token" {
+		t.Errorf("expected token, got %v", response.Botoken)
+	}
+}
+
+func TestSendWithoutConnection(t *testing.T) {
+	// eg. after Leave or while reconnecting
+	session := &ZoomSession{
+		JoinInfo: &JoinConferenceResponse{},
+		events:   newEventBus(),
+		requests: newPendingRequests(),
+	}
+	if err := session.SendChatMessage(EVERYONE_CHAT_ID, "hello"); err != ErrNotConnected {
+		t.Errorf("expected ErrNotConnected, got %v", err)
+	}
+	if _, err := session.Request(context.Background(), WS_CONF_END_REQ, ConferenceEndRequest{}); err != ErrNotConnected {
+		t.Errorf("expected ErrNotConnected, got %v", err)
+	}
+}
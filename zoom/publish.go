@@ -0,0 +1,346 @@
+package zoom
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/h264"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/opus"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/streampkt"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	VIDEO_CLOCK_RATE = 90000
+	// used when PublishVideo is not told the frame rate
+	DEFAULT_FRAME_RATE = 25
+
+	// bytes in front of the rtp packet in video and screenshare messages, what's in there is unknown
+	VIDEO_PKT_HEADER_LENGTH       = 28
+	SCREENSHARE_PKT_HEADER_LENGTH = 4
+)
+
+// zoom tells us which ssrc to send our audio with once we joined the voip channel (untested)
+func (session *ZoomSession) updateAudioSsrc(indication *SSRCIndication) {
+	session.setSendingSsrc(rtp.STREAM_TYPE_AUDIO, indication.SSRC)
+}
+
+// same for video once we turned it on (untested)
+func (session *ZoomSession) updateVideoSsrc(indication *SSRCIndication) {
+	session.setSendingSsrc(rtp.STREAM_TYPE_VIDEO, indication.SSRC)
+}
+
+// zoom assigns us an ssrc for sharing our screen after SetShareStatus(true, ...) (untested)
+func (session *ZoomSession) updateSharingSsrc(response *SharingAssignedSendingSsrcResponse) {
+	session.setSendingSsrc(rtp.STREAM_TYPE_SCREENSHARE, response.SSRC)
+}
+
+// screenshares are encrypted with a key of their own
+func (session *ZoomSession) updateSharingEncryptKey(indication *SharingEncryptKeyIndication) {
+	session.updatePublishState(func() {
+		session.sharingEncryptKey = indication.EncryptKey
+	})
+}
+
+func (session *ZoomSession) setSendingSsrc(streamType rtp.StreamType, ssrc int) {
+	session.updatePublishState(func() {
+		if session.sendingSsrcs == nil {
+			session.sendingSsrcs = make(map[rtp.StreamType]int)
+		}
+		session.sendingSsrcs[streamType] = ssrc
+	})
+}
+
+// resetSharingState forgets the ssrc and key of our last screenshare, zoom hands out new ones for the next
+func (session *ZoomSession) resetSharingState() {
+	session.updatePublishState(func() {
+		delete(session.sendingSsrcs, rtp.STREAM_TYPE_SCREENSHARE)
+		session.sharingEncryptKey = ""
+	})
+}
+
+// resetPublishState forgets all sending ssrcs and the sharing key, none of them carry over to another meeting
+func (session *ZoomSession) resetPublishState() {
+	session.updatePublishState(func() {
+		session.sendingSsrcs = nil
+		session.sharingEncryptKey = ""
+	})
+}
+
+func (session *ZoomSession) getSendingSsrc(streamType rtp.StreamType) int {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.sendingSsrcs[streamType]
+}
+
+func (session *ZoomSession) updatePublishState(update func()) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	update()
+	// wake up everyone in waitForPublishState
+	if session.publishStateChanged != nil {
+		close(session.publishStateChanged)
+		session.publishStateChanged = nil
+	}
+}
+
+// waitForPublishState waits until ready returns true, it is called with the session locked
+func (session *ZoomSession) waitForPublishState(ctx context.Context, ready func() bool) error {
+	for {
+		session.mu.Lock()
+		if ready() {
+			session.mu.Unlock()
+			return nil
+		}
+		if session.publishStateChanged == nil {
+			session.publishStateChanged = make(chan struct{})
+		}
+		changed := session.publishStateChanged
+		session.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (session *ZoomSession) waitForSendingSsrc(ctx context.Context, streamType rtp.StreamType) (int, error) {
+	var ssrc int
+	err := session.waitForPublishState(ctx, func() bool {
+		ssrc = session.sendingSsrcs[streamType]
+		return ssrc != 0
+	})
+	return ssrc, err
+}
+
+/*
+newEncoder sets up encryption with our own key, everyone decrypts what we send with the key derived from our zoomID.
+Without a sharedMeetingKey the one the decoder uses is taken, screenshares get a key of their own and must not
+replace the one we receive the screenshares of others with.
+*/
+func (streams *ZoomStreams) newEncoder(ssrc int, sharedMeetingKey []byte) (*rtp.ZoomRtpEncoder, error) {
+	if sharedMeetingKey == nil {
+		var err error
+		sharedMeetingKey, err = streams.getDecoder().ParticipantRoster.GetSharedMeetingKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+	secretNonce, err := ZoomEscapedBase64Decode(streams.session.JoinInfo.ZoomID)
+	if err != nil {
+		return nil, err
+	}
+	return rtp.NewZoomRtpEncoder(streams.streamType, sharedMeetingKey, secretNonce, uint32(ssrc))
+}
+
+// waitUntil paces publishing, zoom drops what it can't play so we must not get ahead of real time
+func waitUntil(ctx context.Context, deadline time.Time) error {
+	select {
+	case <-time.After(time.Until(deadline)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*
+PublishAudio plays the opus packets of the source into the meeting in real time, it returns once the source
+is drained or the context is cancelled. Use opus.NewOggReader for .opus files and opus.NewPCMSource for raw PCM.
+
+Unmute yourself with SetAudioMuted(false) first, this joins the voip channel after which zoom assigns us an ssrc.
+The shared meeting key has to be set with SetSharedMeetingKey as for receiving.
+*/
+func (streams *ZoomStreams) PublishAudio(ctx context.Context, source opus.AudioSource) error {
+	if streams.streamType != rtp.STREAM_TYPE_AUDIO {
+		return ErrWrongStreamType
+	}
+	ssrc := streams.session.getSendingSsrc(rtp.STREAM_TYPE_AUDIO)
+	if ssrc == 0 {
+		return ErrNoSendingSsrc
+	}
+	encoder, err := streams.newEncoder(ssrc, nil)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var sent time.Duration
+	for {
+		packet, err := source.ReadPacket()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sampleCount, err := opus.PacketSamples(packet)
+		if err != nil {
+			return err
+		}
+
+		rawPkts, err := encoder.Encode(packet, true, uint32(sampleCount))
+		if err != nil {
+			return err
+		}
+		err = streams.writeRtp(rawPkts)
+		if err != nil {
+			return err
+		}
+
+		sent += time.Duration(sampleCount) * time.Second / opus.PCM_SAMPLE_RATE
+		err = waitUntil(ctx, start.Add(sent))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/*
+PublishVideo shows the H.264 frames of the source as our camera, it returns once the source is drained or the
+context is cancelled. Use h264.NewAnnexBReader for .h264 files, frames are sent at the given frame rate
+(DEFAULT_FRAME_RATE if 0). Stick to the baseline profile without B-frames, that is what the web client sends.
+
+It turns our video on and waits for zoom to assign us an ssrc. When someone asks for a keyframe the source is
+asked for one if it implements h264.KeyFrameRequester. Otherwise frames are dropped until the source's next
+keyframe, the frames in between refer to frames the receiver lost so they would only show up corrupted.
+*/
+func (streams *ZoomStreams) PublishVideo(ctx context.Context, source h264.VideoSource, frameRate int) error {
+	if streams.streamType != rtp.STREAM_TYPE_VIDEO {
+		return ErrWrongStreamType
+	}
+	if frameRate <= 0 {
+		frameRate = DEFAULT_FRAME_RATE
+	}
+
+	session := streams.session
+	err := session.SetVideoMuted(false)
+	if err != nil {
+		return err
+	}
+	ssrc, err := session.waitForSendingSsrc(ctx, rtp.STREAM_TYPE_VIDEO)
+	if err != nil {
+		return err
+	}
+	encoder, err := streams.newEncoder(ssrc, nil)
+	if err != nil {
+		return err
+	}
+
+	var keyFrameRequested int32
+	subscription, err := session.On(WS_VIDEO_KEY_FRAME_REQ, func(request *VideoKeyFrameRequest) {
+		if request.SSRC == 0 || request.SSRC == ssrc {
+			atomic.StoreInt32(&keyFrameRequested, 1)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer subscription.Unsubscribe()
+
+	return streams.publishFrames(ctx, encoder, source, frameRate, &keyFrameRequested, nil)
+}
+
+/*
+publishFrames sends the frames of the source in real time, while paused is set frames are read but dropped.
+After a pause, and after a keyframe request the source can't answer, it waits for the next keyframe of the
+source. Sending an old keyframe instead doesn't work, the frames after it refer to the ones that came before.
+*/
+func (streams *ZoomStreams) publishFrames(ctx context.Context, encoder *rtp.ZoomRtpEncoder, source h264.VideoSource, frameRate int, keyFrameRequested *int32, paused *int32) error {
+	requester, canRequest := source.(h264.KeyFrameRequester)
+	frameDuration := time.Second / time.Duration(frameRate)
+
+	// nobody can decode anything before the first keyframe
+	waitingForKeyFrame := true
+	next := time.Now()
+	for {
+		frame, err := source.ReadFrame()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		keyFrame := h264.IsKeyFrame(frame)
+		if keyFrame {
+			// the resolution extension of screenshares follows the stream, e.g. when a window is resized
+			sps, err := h264.ParseSPS(h264.FindNalu(frame, h264.NALU_TYPE_SPS))
+			if err == nil {
+				encoder.SetResolution(sps.Width, sps.Height)
+			}
+		}
+		if paused != nil && atomic.LoadInt32(paused) == 1 {
+			// what comes after the pause refers to frames nobody got
+			waitingForKeyFrame = true
+		} else {
+			if atomic.SwapInt32(keyFrameRequested, 0) == 1 && !keyFrame {
+				if canRequest {
+					requester.RequestKeyFrame()
+				} else {
+					waitingForKeyFrame = true
+				}
+			}
+			if keyFrame {
+				waitingForKeyFrame = false
+			}
+		}
+
+		if !waitingForKeyFrame {
+			rawPkts, err := encoder.Encode(frame, keyFrame, uint32(VIDEO_CLOCK_RATE/frameRate))
+			if err != nil {
+				return err
+			}
+			err = streams.writeRtp(rawPkts)
+			if err != nil {
+				return err
+			}
+		}
+
+		next = next.Add(frameDuration)
+		err = waitUntil(ctx, next)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeRtp wraps the rtp packets the way the receiving side expects them, see StartReceiveChannel (untested)
+func (streams *ZoomStreams) writeRtp(rawPkts [][]byte) error {
+	for _, rawPkt := range rawPkts {
+		var message []byte
+		switch streams.streamType {
+		case rtp.STREAM_TYPE_AUDIO:
+			zoomPkt := &streampkt.ZoomAudioPkt{Rtp: rawPkt}
+			message = zoomPkt.Marshal()
+		case rtp.STREAM_TYPE_VIDEO:
+			message = make([]byte, VIDEO_PKT_HEADER_LENGTH, VIDEO_PKT_HEADER_LENGTH+len(rawPkt))
+			message[0] = RTP_VIDEO_PKT
+			message = append(message, rawPkt...)
+		case rtp.STREAM_TYPE_SCREENSHARE:
+			message = make([]byte, SCREENSHARE_PKT_HEADER_LENGTH, SCREENSHARE_PKT_HEADER_LENGTH+len(rawPkt))
+			message[0] = RTP_SCREENSHARE_PKT
+			message = append(message, rawPkt...)
+		}
+		err := streams.writeSend(message)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (streams *ZoomStreams) writeSend(data []byte) error {
+	streams.mu.Lock()
+	connection := streams.send
+	streams.mu.Unlock()
+
+	streams.sendMu.Lock()
+	defer streams.sendMu.Unlock()
+	return connection.WriteMessage(websocket.BinaryMessage, data)
+}
@@ -0,0 +1,272 @@
+package zoom
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp/ext"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/streampkt"
+	"github.com/gorilla/websocket"
+	pionrtp "github.com/pion/rtp"
+)
+
+type packetSource struct {
+	packets [][]byte
+}
+
+func (source *packetSource) ReadPacket() ([]byte, error) {
+	if len(source.packets) == 0 {
+		return nil, io.EOF
+	}
+	packet := source.packets[0]
+	source.packets = source.packets[1:]
+	return packet, nil
+}
+
+func TestPublishAudio(t *testing.T) {
+	received := make(chan []byte, 10)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+		for {
+			_, p, err := connection.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- p
+		}
+	}))
+	defer server.Close()
+
+	connection, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer connection.Close()
+
+	zoomID := "ESIzRFVmd4iZqrvM3e7_AA"
+	secretNonce, _ := ZoomEscapedBase64Decode(zoomID)
+	sharedMeetingKey := bytes.Repeat([]byte{0x11}, 32)
+	userID := 16778240
+	ssrc := 16778241
+
+	session := &ZoomSession{
+		JoinInfo:     &JoinConferenceResponse{UserID: userID, ZoomID: zoomID},
+		sendingSsrcs: map[rtp.StreamType]int{rtp.STREAM_TYPE_AUDIO: ssrc},
+	}
+	streams := &ZoomStreams{
+		send:       connection,
+		decoder:    rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_AUDIO),
+		session:    session,
+		streamType: rtp.STREAM_TYPE_AUDIO,
+	}
+	streams.decoder.ParticipantRoster.SetSharedMeetingKey(sharedMeetingKey)
+
+	packets := [][]byte{{0xF8, 0xFF, 0xFE}, {0xF8, 0x01}, {0xF8, 0x02}}
+	start := time.Now()
+	err = streams.PublishAudio(context.Background(), &packetSource{packets: append([][]byte{}, packets...)})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if time.Since(start) < 60*time.Millisecond {
+		t.Errorf("expected 60ms of audio to take 60ms, took %v", time.Since(start))
+	}
+
+	// everyone else in the meeting decodes us like this
+	decoder := rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_AUDIO)
+	decoder.ParticipantRoster.SetSharedMeetingKey(sharedMeetingKey)
+	decoder.ParticipantRoster.AddParticipant(userID, secretNonce)
+	decoder.ParticipantRoster.AddSsrcForParticipant(userID, ssrc)
+	var decoded [][]byte
+	for range packets {
+		select {
+		case p := <-received:
+			zoomPkt := &streampkt.ZoomAudioPkt{}
+			err := zoomPkt.Unmarshal(p)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sample, err := decoder.Decode(zoomPkt.Rtp)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if sample != nil {
+				decoded = append(decoded, sample.Data)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("expected a packet per opus packet")
+			return
+		}
+	}
+	// the sample builder holds on to the last one
+	if len(decoded) != 2 || !bytes.Equal(decoded[0], packets[0]) || !bytes.Equal(decoded[1], packets[1]) {
+		t.Errorf("expected %v, got %v", packets[:2], decoded)
+	}
+}
+
+func TestPublishAudioNeedsSsrc(t *testing.T) {
+	streams := &ZoomStreams{
+		session:    &ZoomSession{JoinInfo: &JoinConferenceResponse{}},
+		streamType: rtp.STREAM_TYPE_AUDIO,
+	}
+	err := streams.PublishAudio(context.Background(), &packetSource{})
+	if err != ErrNoSendingSsrc {
+		t.Errorf("expected ErrNoSendingSsrc, got %v", err)
+	}
+}
+
+type frameSource struct {
+	frames [][]byte
+}
+
+func (source *frameSource) ReadFrame() ([]byte, error) {
+	if len(source.frames) == 0 {
+		return nil, io.EOF
+	}
+	frame := source.frames[0]
+	source.frames = source.frames[1:]
+	return frame, nil
+}
+
+func TestPublishVideo(t *testing.T) {
+	ssrc := 16778242
+	media := make(chan []byte, 100)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+
+		if r.URL.Path == "/media" {
+			for {
+				_, p, err := connection.ReadMessage()
+				if err != nil {
+					return
+				}
+				media <- p
+			}
+		}
+		for {
+			request := &GenericZoomMessage{}
+			err := connection.ReadJSON(request)
+			if err != nil {
+				return
+			}
+			if request.Evt == WS_VIDEO_MUTE_VIDEO_REQ {
+				connection.WriteJSON(&GenericZoomMessage{Evt: WS_VIDEO_SSRC_INDICATION, Body: []byte(`{"ssrc":16778242}`)})
+				// someone joins right after we started and needs a keyframe
+				time.Sleep(50 * time.Millisecond)
+				connection.WriteJSON(&GenericZoomMessage{Evt: WS_VIDEO_KEY_FRAME_REQ, Body: []byte(`{"ssrc":16778242}`)})
+			}
+		}
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	connection, _, err := websocket.DefaultDialer.Dial(url+"/session", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer connection.Close()
+	send, _, err := websocket.DefaultDialer.Dial(url+"/media", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer send.Close()
+
+	session := &ZoomSession{
+		JoinInfo:            &JoinConferenceResponse{ZoomID: "ESIzRFVmd4iZqrvM3e7_AA"},
+		events:              newEventBus(),
+		requests:            newPendingRequests(),
+		websocketConnection: connection,
+	}
+	session.On(WS_VIDEO_SSRC_INDICATION, session.updateVideoSsrc)
+	go session.readLoop(connection, func() {})
+
+	streams := &ZoomStreams{
+		send:       send,
+		decoder:    rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_VIDEO),
+		session:    session,
+		streamType: rtp.STREAM_TYPE_VIDEO,
+	}
+	streams.decoder.ParticipantRoster.SetSharedMeetingKey(bytes.Repeat([]byte{0x11}, 32))
+
+	keyFrame := []byte{0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1e, 0, 0, 0, 1, 0x68, 0xce, 0x3c, 0x80, 0, 0, 0, 1, 0x65, 0x88, 0x84}
+	slice := []byte{0, 0, 0, 1, 0x41, 0x9a, 0x02}
+	frames := [][]byte{slice, keyFrame}
+	for i := 0; i < 10; i++ {
+		frames = append(frames, slice)
+	}
+	frames = append(frames, keyFrame, slice, slice)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = streams.PublishVideo(ctx, &frameSource{frames: frames}, 50)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// the slice before the first keyframe is skipped, after the request the slices are dropped until the next keyframe
+	var keyFrames []bool
+	seen, second := 0, 0
+	for seen < 2 || len(keyFrames) < second+3 {
+		var p []byte
+		select {
+		case p = <-media:
+		case <-time.After(5 * time.Second):
+			t.Errorf("expected the second keyframe and the 2 slices after it, got %v", keyFrames)
+			return
+		}
+		if p[0] != RTP_VIDEO_PKT {
+			t.Errorf("expected a video packet, got %x", p[0])
+			return
+		}
+		packet := &pionrtp.Packet{}
+		err := packet.Unmarshal(p[VIDEO_PKT_HEADER_LENGTH:])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if packet.SSRC != uint32(ssrc) {
+			t.Errorf("expected ssrc %v, got %v", ssrc, packet.SSRC)
+		}
+		frameInfo := &ext.RtpExtFrameInfo{}
+		err = frameInfo.Unmarshal(packet.GetExtension(ext.RTP_EXTENSION_ID_VIDEO_FRAME_INFO))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		keyFrames = append(keyFrames, frameInfo.Independent)
+		if frameInfo.Independent {
+			seen++
+			second = len(keyFrames) - 1
+		}
+	}
+
+	if !keyFrames[0] {
+		t.Errorf("expected to start with a keyframe, got %v", keyFrames)
+	}
+	if len(keyFrames) >= 14 {
+		t.Errorf("expected slices to be dropped after the keyframe request, got %v", keyFrames)
+	}
+}
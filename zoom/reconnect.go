@@ -0,0 +1,108 @@
+package zoom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+/*
+ReconnectPolicy decides how often and how fast we try to get back into the meeting when the signaling
+websocket drops. We rejoin with the ZoomID and participant ID from the previous join response so zoom
+treats us as the same participant instead of a new one.
+*/
+type ReconnectPolicy struct {
+	// 0 means we keep trying forever
+	MaxAttempts  int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxAttempts:  10,
+	InitialDelay: 1 * time.Second,
+	MaxDelay:     1 * time.Minute,
+	Multiplier:   2,
+}
+
+// Backoff returns how long to wait before the given attempt, attempts start at 1
+func (policy *ReconnectPolicy) Backoff(attempt int) time.Duration {
+	delay := float64(policy.InitialDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= policy.Multiplier
+		if policy.MaxDelay > 0 && delay >= float64(policy.MaxDelay) {
+			return policy.MaxDelay
+		}
+	}
+	return time.Duration(delay)
+}
+
+func (policy *ReconnectPolicy) canRetry(attempt int) bool {
+	return policy.MaxAttempts == 0 || attempt <= policy.MaxAttempts
+}
+
+// SessionReconnecting is emitted before every attempt to get back into the meeting
+type SessionReconnecting struct {
+	Attempt int
+	Delay   time.Duration
+	// why the previous connection (or attempt) failed
+	Err error
+}
+
+// SessionReconnected is emitted once we are connected to the meeting again
+type SessionReconnected struct {
+	Attempt int
+}
+
+// shouldReconnect filters out the failures where reconnecting makes no sense
+func (session *ZoomSession) shouldReconnect(err error) bool {
+	if session.ReconnectPolicy == nil || err == nil || err == ErrMeetingEnded {
+		return false
+	}
+	// zoom closed the connection on purpose, for instance because we were removed from the meeting
+	if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		return false
+	}
+	return true
+}
+
+// reconnect re-dials the meeting according to the ReconnectPolicy, it returns a nil connection without error when the session was closed in the meantime
+func (session *ZoomSession) reconnect(ctx context.Context, cause error) (*websocket.Conn, error) {
+	policy := session.ReconnectPolicy
+
+	attempt := 1
+	for ; policy.canRetry(attempt); attempt++ {
+		delay := policy.Backoff(attempt)
+		log.Printf("Connection lost (%v), reconnecting in %v (attempt %d)", cause, delay, attempt)
+		session.emit(LOCAL_SESSION_RECONNECTING, &SessionReconnecting{
+			Attempt: attempt,
+			Delay:   delay,
+			Err:     cause,
+		})
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, nil
+		}
+
+		// rejoin as the same participant if we made it into the meeting before
+		connection, err := session.dialWebsocket(ctx, session.JoinInfo != nil)
+		if err == nil {
+			session.emit(LOCAL_SESSION_RECONNECTED, &SessionReconnected{
+				Attempt: attempt,
+			})
+			return connection, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+		cause = err
+	}
+
+	return nil, fmt.Errorf("giving up after %d reconnect attempts: %w", attempt-1, cause)
+}
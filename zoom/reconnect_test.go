@@ -0,0 +1,59 @@
+package zoom
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReconnectPolicyBackoff(t *testing.T) {
+	policy := &ReconnectPolicy{
+		InitialDelay: 1 * time.Second,
+		MaxDelay:     10 * time.Second,
+		Multiplier:   2,
+	}
+
+	expected := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, delay := range expected {
+		backoff := policy.Backoff(i + 1)
+		if backoff != delay {
+			t.Errorf("attempt %v: expected backoff %v, got %v", i+1, delay, backoff)
+		}
+	}
+}
+
+func TestReconnectPolicyMaxAttempts(t *testing.T) {
+	policy := &ReconnectPolicy{MaxAttempts: 2}
+	if !policy.canRetry(2) || policy.canRetry(3) {
+		t.Error("expected exactly 2 attempts")
+	}
+
+	unlimited := &ReconnectPolicy{}
+	if !unlimited.canRetry(1000) {
+		t.Error("expected unlimited attempts")
+	}
+}
+
+func TestShouldReconnect(t *testing.T) {
+	session := &ZoomSession{ReconnectPolicy: &DefaultReconnectPolicy}
+
+	if !session.shouldReconnect(errors.New("read tcp: connection reset by peer")) {
+		t.Error("expected to reconnect after a network error")
+	}
+	if !session.shouldReconnect(&websocket.CloseError{Code: websocket.CloseAbnormalClosure}) {
+		t.Error("expected to reconnect after an abnormal closure")
+	}
+	if session.shouldReconnect(&websocket.CloseError{Code: websocket.CloseNormalClosure}) {
+		t.Error("expected not to reconnect after zoom closed the connection")
+	}
+	if session.shouldReconnect(ErrMeetingEnded) {
+		t.Error("expected not to reconnect after the meeting ended")
+	}
+
+	session.ReconnectPolicy = nil
+	if session.shouldReconnect(errors.New("read tcp: connection reset by peer")) {
+		t.Error("expected not to reconnect without a policy")
+	}
+}
@@ -0,0 +1,324 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/opus"
+)
+
+const (
+	STAGE_KIND_SPEAKER     = "speaker"
+	STAGE_KIND_SCREENSHARE = "screenshare"
+
+	TIMELINE_FILE_NAME = "timeline.json"
+	MIXED_FILE_NAME    = "mixed.mkv"
+)
+
+// Timeline describes a composite recording, all offsets are in milliseconds since Start
+type Timeline struct {
+	Start      time.Time           `json:"start"`
+	Recordings []TimelineRecording `json:"recordings"`
+	Stage      []StageEntry        `json:"stage"`
+	// the audio of everyone mixed into a single track, nil until someone was heard
+	Mixed *TimelineRecording `json:"mixed,omitempty"`
+}
+
+// TimelineRecording is one of the files written by the MeetingRecorder
+type TimelineRecording struct {
+	File        string `json:"file"`
+	UserID      int    `json:"userID"`
+	ScreenShare bool   `json:"screenShare"`
+	StartMs     int64  `json:"startMs"`
+	Video       bool   `json:"video"`
+	Audio       bool   `json:"audio"`
+}
+
+// StageEntry says whose track should be shown big between StartMs and EndMs
+type StageEntry struct {
+	StartMs int64  `json:"startMs"`
+	EndMs   int64  `json:"endMs"`
+	Kind    string `json:"kind"`
+	UserID  int    `json:"userID"`
+}
+
+/*
+CompositeRecorder records everyone like MeetingRecorder and keeps a timeline of who was "on stage": whoever is
+sharing their screen, otherwise the active speaker. On Close the timeline is written as timeline.json next to the
+recordings so a "gallery view" can be rendered offline, e.g. with ffmpeg. The audio of everyone is mixed into
+mixed.mkv as 16-bit PCM, the renderer only has to lay it under the video.
+
+Pass it as the sink to CreateZoomVideoStreams, CreateZoomScreenShareStreams and CreateZoomAudioStreams.
+*/
+type CompositeRecorder struct {
+	*MeetingRecorder
+	directory     string
+	subscriptions []*zoom.Subscription
+
+	mu       sync.Mutex
+	timeline Timeline
+	speaker  int
+	sharer   int
+	// the entry that is still on stage, nil when there is nobody
+	current *StageEntry
+	// started with the first audio so a meeting without any doesn't get a silent mix
+	mixer  *opus.AudioMixer
+	mixed  *mixedRecording
+	closed bool
+}
+
+func NewCompositeRecorder(session *zoom.ZoomSession, directory string) (*CompositeRecorder, error) {
+	composite := newCompositeRecorder(Files(directory), time.Now)
+	composite.directory = directory
+
+	speakerSubscription, err := session.On(zoom.WS_AUDIO_ASN_INDICATION, composite.onActiveSpeaker)
+	if err != nil {
+		return nil, err
+	}
+	sharingSubscription, err := session.On(zoom.WS_SHARING_STATUS_INDICATION, composite.onSharingStatus)
+	if err != nil {
+		speakerSubscription.Unsubscribe()
+		return nil, err
+	}
+	composite.subscriptions = []*zoom.Subscription{speakerSubscription, sharingSubscription}
+
+	return composite, nil
+}
+
+func newCompositeRecorder(newWriter WriterFactory, now func() time.Time) *CompositeRecorder {
+	composite := &CompositeRecorder{
+		MeetingRecorder: NewMeetingRecorder(newWriter),
+	}
+	composite.MeetingRecorder.now = now
+	composite.MeetingRecorder.segmentStarted = composite.onSegment
+	composite.mixed = &mixedRecording{newWriter: newWriter, segmentStarted: composite.onSegment}
+	composite.timeline = Timeline{
+		Start:      now(),
+		Recordings: []TimelineRecording{},
+		Stage:      []StageEntry{},
+	}
+	return composite
+}
+
+func (composite *CompositeRecorder) onSegment(segment Segment) {
+	composite.mu.Lock()
+	defer composite.mu.Unlock()
+
+	recording := TimelineRecording{
+		File:        segment.Name(),
+		UserID:      segment.UserID,
+		ScreenShare: segment.ScreenShare,
+		StartMs:     composite.offset(segment.Start),
+		Video:       segment.HasVideo,
+		Audio:       segment.HasAudio,
+	}
+	if segment.Mixed {
+		composite.timeline.Mixed = &recording
+		return
+	}
+	composite.timeline.Recordings = append(composite.timeline.Recordings, recording)
+}
+
+// OnPCM receives the decoded audio of everyone from the audio streams and mixes it
+func (composite *CompositeRecorder) OnPCM(userID int, samples []int16, timestamp uint32) {
+	composite.mu.Lock()
+	if composite.closed {
+		composite.mu.Unlock()
+		return
+	}
+	if composite.mixer == nil {
+		composite.mixed.start = composite.MeetingRecorder.now()
+		composite.mixer = opus.NewAudioMixer(composite.mixed, 0)
+	}
+	mixer := composite.mixer
+	composite.mu.Unlock()
+
+	mixer.OnPCM(userID, samples, timestamp)
+}
+
+// CloseParticipant finishes the recordings of someone who left, what they said stays in the mix
+func (composite *CompositeRecorder) CloseParticipant(userID int) error {
+	composite.mu.Lock()
+	mixer := composite.mixer
+	composite.mu.Unlock()
+
+	if mixer != nil {
+		mixer.RemoveParticipant(userID)
+	}
+	return composite.MeetingRecorder.CloseParticipant(userID)
+}
+
+func (composite *CompositeRecorder) onActiveSpeaker(indication *zoom.AudioAsnIndication) {
+	composite.mu.Lock()
+	defer composite.mu.Unlock()
+
+	composite.speaker = indication.Asn1
+	composite.updateStage()
+}
+
+// ActiveNodeID is 0 once nobody is sharing anymore
+func (composite *CompositeRecorder) onSharingStatus(indication *zoom.SharingStatusIndication) {
+	composite.mu.Lock()
+	defer composite.mu.Unlock()
+
+	composite.sharer = indication.ActiveNodeID
+	composite.updateStage()
+}
+
+func (composite *CompositeRecorder) updateStage() {
+	kind, userID := STAGE_KIND_SPEAKER, composite.speaker
+	if composite.sharer != 0 {
+		kind, userID = STAGE_KIND_SCREENSHARE, composite.sharer
+	}
+	if composite.current != nil && composite.current.Kind == kind && composite.current.UserID == userID {
+		return
+	}
+
+	now := composite.offset(composite.MeetingRecorder.now())
+	composite.endStage(now)
+	if userID != 0 {
+		composite.current = &StageEntry{StartMs: now, Kind: kind, UserID: userID}
+	}
+}
+
+func (composite *CompositeRecorder) endStage(at int64) {
+	if composite.current == nil {
+		return
+	}
+	composite.current.EndMs = at
+	composite.timeline.Stage = append(composite.timeline.Stage, *composite.current)
+	composite.current = nil
+}
+
+func (composite *CompositeRecorder) offset(at time.Time) int64 {
+	return at.Sub(composite.timeline.Start).Milliseconds()
+}
+
+// Timeline returns the timeline so far, whoever is on stage right now is left out until they are replaced
+func (composite *CompositeRecorder) Timeline() Timeline {
+	composite.mu.Lock()
+	defer composite.mu.Unlock()
+
+	timeline := composite.timeline
+	timeline.Recordings = append([]TimelineRecording{}, timeline.Recordings...)
+	timeline.Stage = append([]StageEntry{}, timeline.Stage...)
+	if timeline.Mixed != nil {
+		mixed := *timeline.Mixed
+		timeline.Mixed = &mixed
+	}
+	return timeline
+}
+
+// Close finishes all recordings and writes the timeline
+func (composite *CompositeRecorder) Close() error {
+	for _, subscription := range composite.subscriptions {
+		subscription.Unsubscribe()
+	}
+	err := composite.MeetingRecorder.Close()
+
+	composite.mu.Lock()
+	composite.endStage(composite.offset(composite.MeetingRecorder.now()))
+	composite.closed = true
+	mixer := composite.mixer
+	composite.mu.Unlock()
+
+	if mixer != nil {
+		// passes on what is still waiting for late packets
+		mixer.Close()
+	}
+	mixErr := composite.mixed.close()
+	if err == nil {
+		err = mixErr
+	}
+
+	if composite.directory == "" {
+		return err
+	}
+	data, jsonErr := json.MarshalIndent(composite.Timeline(), "", "  ")
+	if jsonErr != nil {
+		return jsonErr
+	}
+	writeErr := ioutil.WriteFile(filepath.Join(composite.directory, TIMELINE_FILE_NAME), data, 0644)
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// mixedRecording writes the mix of an AudioMixer into its own file, it is opened with the first frame
+type mixedRecording struct {
+	newWriter      WriterFactory
+	segmentStarted func(segment Segment)
+	// when the mixer started, its timestamps count samples from here
+	start time.Time
+
+	mu     sync.Mutex
+	file   io.WriteCloser
+	writer *MatroskaWriter
+	failed bool
+	buffer []byte
+}
+
+func (mixed *mixedRecording) OnPCM(userID int, samples []int16, timestamp uint32) {
+	mixed.mu.Lock()
+	defer mixed.mu.Unlock()
+
+	if mixed.failed {
+		return
+	}
+	err := mixed.write(samples, timestamp)
+	if err != nil {
+		// don't log the same error 50 times a second
+		mixed.failed = true
+		log.Printf("Recording the mixed audio failed: %+v", err)
+	}
+}
+
+func (mixed *mixedRecording) write(samples []int16, timestamp uint32) error {
+	if mixed.writer == nil {
+		segment := Segment{
+			Start:    mixed.start,
+			HasAudio: true,
+			Mixed:    true,
+		}
+		file, err := mixed.newWriter(segment)
+		if err != nil {
+			return err
+		}
+		writer, err := NewMatroskaWriter(file, nil, &AudioTrack{Channels: opus.PCM_CHANNELS, PCM: true})
+		if err != nil {
+			file.Close()
+			return err
+		}
+		mixed.file = file
+		mixed.writer = writer
+		mixed.segmentStarted(segment)
+	}
+
+	mixed.buffer = mixed.buffer[:0]
+	for _, sample := range samples {
+		mixed.buffer = binary.LittleEndian.AppendUint16(mixed.buffer, uint16(sample))
+	}
+	ms := int64(timestamp) * 1000 / (opus.PCM_SAMPLE_RATE * opus.PCM_CHANNELS)
+	return mixed.writer.WriteAudio(ms, mixed.buffer)
+}
+
+func (mixed *mixedRecording) close() error {
+	mixed.mu.Lock()
+	defer mixed.mu.Unlock()
+
+	if mixed.file == nil {
+		return nil
+	}
+	err := mixed.file.Close()
+	mixed.file = nil
+	mixed.writer = nil
+	return err
+}
@@ -0,0 +1,113 @@
+package recorder
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/opus"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+)
+
+func TestCompositeRecorderTimeline(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	composite := newCompositeRecorder(func(segment Segment) (io.WriteCloser, error) {
+		return &bufferCloser{}, nil
+	}, func() time.Time {
+		return now
+	})
+	advance := func(ms int) {
+		now = now.Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	sps := []byte{0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1e, 0xda, 0x02, 0x80, 0xbf, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x58, 0xba, 0x80}
+	pps := []byte{0, 0, 0, 1, 0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0, 0, 0, 1, 0x65, 0x88, 0x84}
+	keyFrame := append(append(append([]byte{}, sps...), pps...), idr...)
+
+	composite.onActiveSpeaker(&zoom.AudioAsnIndication{Asn1: 1})
+	advance(100)
+	composite.OnSample(&zoom.MediaSample{UserID: 1, StreamType: rtp.STREAM_TYPE_VIDEO, KeyFrame: true, Data: keyFrame})
+	advance(900)
+	composite.onActiveSpeaker(&zoom.AudioAsnIndication{Asn1: 2})
+	advance(1000)
+	composite.onSharingStatus(&zoom.SharingStatusIndication{ActiveNodeID: 1})
+	composite.OnSample(&zoom.MediaSample{UserID: 1, StreamType: rtp.STREAM_TYPE_SCREENSHARE, KeyFrame: true, Data: keyFrame})
+	advance(500)
+	// the screenshare stays on stage while people talk
+	composite.onActiveSpeaker(&zoom.AudioAsnIndication{Asn1: 1})
+	advance(500)
+	composite.onSharingStatus(&zoom.SharingStatusIndication{ActiveNodeID: 0})
+	advance(1000)
+	err := composite.Close()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	timeline := composite.Timeline()
+	expectedStage := []StageEntry{
+		{0, 1000, STAGE_KIND_SPEAKER, 1},
+		{1000, 2000, STAGE_KIND_SPEAKER, 2},
+		{2000, 3000, STAGE_KIND_SCREENSHARE, 1},
+		{3000, 4000, STAGE_KIND_SPEAKER, 1},
+	}
+	if fmt.Sprint(timeline.Stage) != fmt.Sprint(expectedStage) {
+		t.Errorf("expected stage %v, got %v", expectedStage, timeline.Stage)
+	}
+
+	expectedRecordings := []TimelineRecording{
+		{"1-0.mkv", 1, false, 100, true, false},
+		{"1-screen-0.mkv", 1, true, 2000, true, false},
+	}
+	if fmt.Sprint(timeline.Recordings) != fmt.Sprint(expectedRecordings) {
+		t.Errorf("expected recordings %v, got %v", expectedRecordings, timeline.Recordings)
+	}
+}
+
+func TestCompositeRecorderMixesAudio(t *testing.T) {
+	files := make(map[string]*bufferCloser)
+	composite := newCompositeRecorder(func(segment Segment) (io.WriteCloser, error) {
+		file := &bufferCloser{}
+		files[segment.Name()] = file
+		return file, nil
+	}, time.Now)
+
+	samples := make([]int16, opus.MIXER_FRAME_SIZE)
+	for i := range samples {
+		samples[i] = 1000
+	}
+	for i := 0; i < 5; i++ {
+		composite.OnPCM(1, samples, uint32(i*len(samples)))
+		composite.OnPCM(2, samples, uint32(5000+i*len(samples)))
+	}
+	err := composite.Close()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	timeline := composite.Timeline()
+	if timeline.Mixed == nil || timeline.Mixed.File != MIXED_FILE_NAME || !timeline.Mixed.Audio {
+		t.Errorf("expected the mix in the timeline, got %+v", timeline.Mixed)
+		return
+	}
+	if len(timeline.Recordings) != 0 {
+		t.Errorf("expected the mix to be left out of the recordings, got %v", timeline.Recordings)
+	}
+
+	mixed := files[MIXED_FILE_NAME]
+	if mixed == nil {
+		t.Error("expected the mix to be written")
+		return
+	}
+	recording := parseRecording(t, mixed.Bytes())
+	if fmt.Sprint(recording.codecs) != fmt.Sprint([]string{"A_PCM/INT/LIT"}) {
+		t.Errorf("expected a single PCM track, got %v", recording.codecs)
+	}
+	if len(recording.blocks) < 5 {
+		t.Errorf("expected at least 5 frames of 20ms, got %v", len(recording.blocks))
+	}
+}
@@ -0,0 +1,112 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+)
+
+// matroska element IDs, see https://www.matroska.org/technical/elements.html
+const (
+	EBML_ID_HEADER                = 0x1A45DFA3
+	EBML_ID_VERSION               = 0x4286
+	EBML_ID_READ_VERSION          = 0x42F7
+	EBML_ID_MAX_ID_LENGTH         = 0x42F2
+	EBML_ID_MAX_SIZE_LENGTH       = 0x42F3
+	EBML_ID_DOC_TYPE              = 0x4282
+	EBML_ID_DOC_TYPE_VERSION      = 0x4287
+	EBML_ID_DOC_TYPE_READ_VERSION = 0x4285
+
+	MKV_ID_SEGMENT            = 0x18538067
+	MKV_ID_INFO               = 0x1549A966
+	MKV_ID_TIMECODE_SCALE     = 0x2AD7B1
+	MKV_ID_MUXING_APP         = 0x4D80
+	MKV_ID_WRITING_APP        = 0x5741
+	MKV_ID_TRACKS             = 0x1654AE6B
+	MKV_ID_TRACK_ENTRY        = 0xAE
+	MKV_ID_TRACK_NUMBER       = 0xD7
+	MKV_ID_TRACK_UID          = 0x73C5
+	MKV_ID_TRACK_TYPE         = 0x83
+	MKV_ID_FLAG_LACING        = 0x9C
+	MKV_ID_CODEC_ID           = 0x86
+	MKV_ID_CODEC_PRIVATE      = 0x63A2
+	MKV_ID_CODEC_DELAY        = 0x56AA
+	MKV_ID_SEEK_PRE_ROLL      = 0x56BB
+	MKV_ID_VIDEO              = 0xE0
+	MKV_ID_PIXEL_WIDTH        = 0xB0
+	MKV_ID_PIXEL_HEIGHT       = 0xBA
+	MKV_ID_AUDIO              = 0xE1
+	MKV_ID_SAMPLING_FREQUENCY = 0xB5
+	MKV_ID_CHANNELS           = 0x9F
+	MKV_ID_BIT_DEPTH          = 0x6264
+	MKV_ID_CLUSTER            = 0x1F43B675
+	MKV_ID_TIMECODE           = 0xE7
+	MKV_ID_SIMPLE_BLOCK       = 0xA3
+)
+
+// written as the size of elements we stream out without knowing how large they will get
+var unknownSize = []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+func encodeID(id uint32) []byte {
+	switch {
+	case id > 0xFFFFFF:
+		return []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFFFF:
+		return []byte{byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFF:
+		return []byte{byte(id >> 8), byte(id)}
+	default:
+		return []byte{byte(id)}
+	}
+}
+
+// encodeSize writes a variable size integer, the all ones values are reserved so they are skipped
+func encodeSize(size uint64) []byte {
+	length := 1
+	for length < 8 && size >= (uint64(1)<<uint(7*length))-1 {
+		length++
+	}
+	encoded := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		encoded[i] = byte(size)
+		size >>= 8
+	}
+	encoded[0] |= 0x80 >> uint(length-1)
+	return encoded
+}
+
+type ebmlBuffer struct {
+	bytes.Buffer
+}
+
+func (buffer *ebmlBuffer) element(id uint32, data []byte) {
+	buffer.Write(encodeID(id))
+	buffer.Write(encodeSize(uint64(len(data))))
+	buffer.Write(data)
+}
+
+func (buffer *ebmlBuffer) master(id uint32, children func(*ebmlBuffer)) {
+	child := &ebmlBuffer{}
+	children(child)
+	buffer.element(id, child.Bytes())
+}
+
+func (buffer *ebmlBuffer) uint(id uint32, value uint64) {
+	length := 1
+	for length < 8 && value >= uint64(1)<<uint(8*length) {
+		length++
+	}
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	buffer.element(id, data[8-length:])
+}
+
+func (buffer *ebmlBuffer) float(id uint32, value float64) {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(value))
+	buffer.element(id, data)
+}
+
+func (buffer *ebmlBuffer) string(id uint32, value string) {
+	buffer.element(id, []byte(value))
+}
@@ -0,0 +1,249 @@
+package recorder
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/h264"
+)
+
+const (
+	MKV_TRACK_TYPE_VIDEO = 1
+	MKV_TRACK_TYPE_AUDIO = 2
+
+	// block timecodes are 16 bit offsets from the cluster timecode
+	maxClusterDuration = 30000
+	// opus decoders need this much audio before the seek target to converge, in ns
+	opusSeekPreRoll = 80000000
+)
+
+var ErrNoParameterSets = errors.New("keyframe without SPS/PPS, can't describe the video track")
+
+// VideoTrack describes the H.264 track of a recording, it's taken from the first keyframe
+type VideoTrack struct {
+	SPS    []byte
+	PPS    []byte
+	Width  int
+	Height int
+}
+
+// NewVideoTrack reads the track description from an annex B keyframe that carries its parameter sets
+func NewVideoTrack(keyFrame []byte) (*VideoTrack, error) {
+	sps := h264.FindNalu(keyFrame, h264.NALU_TYPE_SPS)
+	pps := h264.FindNalu(keyFrame, h264.NALU_TYPE_PPS)
+	if sps == nil || pps == nil {
+		return nil, ErrNoParameterSets
+	}
+	parsed, err := h264.ParseSPS(sps)
+	if err != nil {
+		return nil, err
+	}
+	return &VideoTrack{
+		SPS:    append([]byte{}, sps...),
+		PPS:    append([]byte{}, pps...),
+		Width:  parsed.Width,
+		Height: parsed.Height,
+	}, nil
+}
+
+// AVCDecoderConfigurationRecord, ISO/IEC 14496-15 5.2.4.1
+func (track *VideoTrack) codecPrivate() []byte {
+	record := []byte{1, track.SPS[1], track.SPS[2], track.SPS[3], 0xFF, 0xE1}
+	record = append(record, byte(len(track.SPS)>>8), byte(len(track.SPS)))
+	record = append(record, track.SPS...)
+	record = append(record, 1, byte(len(track.PPS)>>8), byte(len(track.PPS)))
+	record = append(record, track.PPS...)
+	return record
+}
+
+// AudioTrack describes the audio track of a recording, opus always runs at 48kHz
+type AudioTrack struct {
+	Channels int
+	// 16-bit little endian samples at 48kHz instead of opus, used for the mix of CompositeRecorder
+	PCM bool
+}
+
+// NewAudioTrack reads the channel count from the TOC byte of an opus packet
+func NewAudioTrack(packet []byte) *AudioTrack {
+	channels := 1
+	if len(packet) > 0 && packet[0]&0x04 != 0 {
+		channels = 2
+	}
+	return &AudioTrack{Channels: channels}
+}
+
+// OpusHead, RFC 7845 5.1
+func (track *AudioTrack) codecPrivate() []byte {
+	head := []byte("OpusHead")
+	head = append(head, 1, byte(track.Channels))
+	head = append(head, 0, 0)                   // pre-skip, zoom doesn't tell us
+	head = append(head, 0x80, 0xBB, 0x00, 0x00) // 48000
+	head = append(head, 0, 0)                   // output gain
+	head = append(head, 0)                      // channel mapping family
+	return head
+}
+
+/*
+MatroskaWriter streams H.264 video and opus (or raw PCM) audio into a .mkv file.
+
+The segment and its clusters are written with an unknown size, so nothing has to be patched afterwards and
+a recording that was cut off is still playable. Timestamps are in milliseconds from the start of the file.
+*/
+type MatroskaWriter struct {
+	w     io.Writer
+	video *VideoTrack
+	audio *AudioTrack
+
+	videoNumber byte
+	audioNumber byte
+
+	clusterOpen  bool
+	clusterStart int64
+}
+
+// NewMatroskaWriter writes the header, either of the tracks may be nil
+func NewMatroskaWriter(w io.Writer, video *VideoTrack, audio *AudioTrack) (*MatroskaWriter, error) {
+	if video == nil && audio == nil {
+		return nil, errors.New("a recording needs at least one track")
+	}
+	writer := &MatroskaWriter{
+		w:     w,
+		video: video,
+		audio: audio,
+	}
+
+	header := &ebmlBuffer{}
+	header.master(EBML_ID_HEADER, func(ebml *ebmlBuffer) {
+		ebml.uint(EBML_ID_VERSION, 1)
+		ebml.uint(EBML_ID_READ_VERSION, 1)
+		ebml.uint(EBML_ID_MAX_ID_LENGTH, 4)
+		ebml.uint(EBML_ID_MAX_SIZE_LENGTH, 8)
+		ebml.string(EBML_ID_DOC_TYPE, "matroska")
+		ebml.uint(EBML_ID_DOC_TYPE_VERSION, 4)
+		ebml.uint(EBML_ID_DOC_TYPE_READ_VERSION, 2)
+	})
+	header.Write(encodeID(MKV_ID_SEGMENT))
+	header.Write(unknownSize)
+
+	header.master(MKV_ID_INFO, func(info *ebmlBuffer) {
+		// timecodes in milliseconds
+		info.uint(MKV_ID_TIMECODE_SCALE, 1000000)
+		info.string(MKV_ID_MUXING_APP, "zoomer")
+		info.string(MKV_ID_WRITING_APP, "zoomer")
+	})
+
+	trackNumber := byte(0)
+	header.master(MKV_ID_TRACKS, func(tracks *ebmlBuffer) {
+		if video != nil {
+			trackNumber++
+			writer.videoNumber = trackNumber
+			tracks.master(MKV_ID_TRACK_ENTRY, func(entry *ebmlBuffer) {
+				entry.uint(MKV_ID_TRACK_NUMBER, uint64(trackNumber))
+				entry.uint(MKV_ID_TRACK_UID, uint64(trackNumber))
+				entry.uint(MKV_ID_TRACK_TYPE, MKV_TRACK_TYPE_VIDEO)
+				entry.uint(MKV_ID_FLAG_LACING, 0)
+				entry.string(MKV_ID_CODEC_ID, "V_MPEG4/ISO/AVC")
+				entry.element(MKV_ID_CODEC_PRIVATE, video.codecPrivate())
+				entry.master(MKV_ID_VIDEO, func(settings *ebmlBuffer) {
+					settings.uint(MKV_ID_PIXEL_WIDTH, uint64(video.Width))
+					settings.uint(MKV_ID_PIXEL_HEIGHT, uint64(video.Height))
+				})
+			})
+		}
+		if audio != nil {
+			trackNumber++
+			writer.audioNumber = trackNumber
+			tracks.master(MKV_ID_TRACK_ENTRY, func(entry *ebmlBuffer) {
+				entry.uint(MKV_ID_TRACK_NUMBER, uint64(trackNumber))
+				entry.uint(MKV_ID_TRACK_UID, uint64(trackNumber))
+				entry.uint(MKV_ID_TRACK_TYPE, MKV_TRACK_TYPE_AUDIO)
+				entry.uint(MKV_ID_FLAG_LACING, 0)
+				if audio.PCM {
+					entry.string(MKV_ID_CODEC_ID, "A_PCM/INT/LIT")
+				} else {
+					entry.string(MKV_ID_CODEC_ID, "A_OPUS")
+					entry.element(MKV_ID_CODEC_PRIVATE, audio.codecPrivate())
+					entry.uint(MKV_ID_CODEC_DELAY, 0)
+					entry.uint(MKV_ID_SEEK_PRE_ROLL, opusSeekPreRoll)
+				}
+				entry.master(MKV_ID_AUDIO, func(settings *ebmlBuffer) {
+					settings.float(MKV_ID_SAMPLING_FREQUENCY, 48000)
+					settings.uint(MKV_ID_CHANNELS, uint64(audio.Channels))
+					if audio.PCM {
+						settings.uint(MKV_ID_BIT_DEPTH, 16)
+					}
+				})
+			})
+		}
+	})
+
+	_, err := w.Write(header.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (writer *MatroskaWriter) HasVideo() bool {
+	return writer.video != nil
+}
+
+func (writer *MatroskaWriter) HasAudio() bool {
+	return writer.audio != nil
+}
+
+// WriteVideo writes an annex B access unit, matroska wants the NAL units length prefixed instead
+func (writer *MatroskaWriter) WriteVideo(timestamp int64, keyFrame bool, accessUnit []byte) error {
+	if writer.video == nil {
+		return errors.New("recording has no video track")
+	}
+	data := make([]byte, 0, len(accessUnit)+16)
+	for _, nalu := range h264.SplitAnnexB(accessUnit) {
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(nalu)))
+		data = append(data, length...)
+		data = append(data, nalu...)
+	}
+	// start clusters at keyframes so players can seek to them
+	return writer.writeBlock(writer.videoNumber, timestamp, keyFrame, keyFrame, data)
+}
+
+// WriteAudio writes a single opus packet, or a block of samples for PCM tracks
+func (writer *MatroskaWriter) WriteAudio(timestamp int64, packet []byte) error {
+	if writer.audio == nil {
+		return errors.New("recording has no audio track")
+	}
+	return writer.writeBlock(writer.audioNumber, timestamp, true, false, packet)
+}
+
+func (writer *MatroskaWriter) writeBlock(trackNumber byte, timestamp int64, keyFrame bool, newCluster bool, data []byte) error {
+	if timestamp < 0 {
+		timestamp = 0
+	}
+
+	relative := timestamp - writer.clusterStart
+	if !writer.clusterOpen || relative > maxClusterDuration || relative < -maxClusterDuration || (newCluster && relative > 0) {
+		cluster := &ebmlBuffer{}
+		cluster.Write(encodeID(MKV_ID_CLUSTER))
+		cluster.Write(unknownSize)
+		cluster.uint(MKV_ID_TIMECODE, uint64(timestamp))
+		_, err := writer.w.Write(cluster.Bytes())
+		if err != nil {
+			return err
+		}
+		writer.clusterOpen = true
+		writer.clusterStart = timestamp
+		relative = 0
+	}
+
+	flags := byte(0)
+	if keyFrame {
+		flags |= 0x80
+	}
+	// track number as a 1 byte vint, 16 bit relative timecode, flags
+	block := &ebmlBuffer{}
+	block.element(MKV_ID_SIMPLE_BLOCK, append([]byte{0x80 | trackNumber, byte(relative >> 8), byte(relative), flags}, data...))
+	_, err := writer.w.Write(block.Bytes())
+	return err
+}
@@ -0,0 +1,336 @@
+package recorder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/h264"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+)
+
+const (
+	VIDEO_CLOCK_RATE = 90000
+	AUDIO_CLOCK_RATE = 48000
+
+	// people who don't send video within this long get an audio only recording
+	waitForVideo = 2 * time.Second
+)
+
+// Segment is a single file of a recording, a participant gets a new one whenever the tracks change
+type Segment struct {
+	UserID      int
+	ScreenShare bool
+	// counts up from 0
+	Index int
+	// when the first sample in the file was received
+	Start    time.Time
+	HasVideo bool
+	HasAudio bool
+	// the mix of everyone's audio written by CompositeRecorder, there is only one
+	Mixed bool
+}
+
+// Name is the file name used by Files, <userID>-<index>.mkv, <userID>-screen-<index>.mkv or mixed.mkv
+func (segment Segment) Name() string {
+	if segment.Mixed {
+		return MIXED_FILE_NAME
+	}
+	if segment.ScreenShare {
+		return fmt.Sprintf("%d-screen-%d.mkv", segment.UserID, segment.Index)
+	}
+	return fmt.Sprintf("%d-%d.mkv", segment.UserID, segment.Index)
+}
+
+// WriterFactory opens the file for a segment of a recording
+type WriterFactory func(segment Segment) (io.WriteCloser, error)
+
+// Files writes the recordings into a directory, see Segment.Name
+func Files(directory string) WriterFactory {
+	return func(segment Segment) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(directory, segment.Name()))
+	}
+}
+
+/*
+rtpClock turns the rtp timestamps of a track into wall clock time.
+
+Audio and video come in on different streams with unrelated rtp timestamps and zoom doesn't send us sender
+reports to line them up, so every track is anchored to the moment its first sample arrived and advances by
+its rtp timestamps from there on. This keeps jitter on the network out of the recording.
+*/
+type rtpClock struct {
+	rate     int64
+	started  bool
+	last     uint32
+	extended int64
+	anchor   time.Time
+}
+
+func (clock *rtpClock) toTime(timestamp uint32, now time.Time) time.Time {
+	if !clock.started {
+		clock.started = true
+		clock.last = timestamp
+		clock.anchor = now
+		return now
+	}
+	// the signed difference takes care of wrap arounds
+	clock.extended += int64(int32(timestamp - clock.last))
+	clock.last = timestamp
+	return clock.anchor.Add(time.Duration(clock.extended * int64(time.Second) / clock.rate))
+}
+
+type pendingAudio struct {
+	at     time.Time
+	packet []byte
+}
+
+type participantRecording struct {
+	userID      int
+	screenShare bool
+	segment     int
+	start       time.Time
+	file        io.WriteCloser
+	writer      *MatroskaWriter
+
+	video      rtpClock
+	audio      rtpClock
+	sps        []byte
+	audioTrack *AudioTrack
+	// audio received before we know whether there will be video
+	pendingAudio []pendingAudio
+	// audio showed up in a video only recording, switch files at the next keyframe
+	rotateAtKeyFrame bool
+}
+
+func newParticipantRecording(key recordingKey, segment int) *participantRecording {
+	return &participantRecording{
+		userID:      key.userID,
+		screenShare: key.screenShare,
+		segment:     segment,
+		video:       rtpClock{rate: VIDEO_CLOCK_RATE},
+		audio:       rtpClock{rate: AUDIO_CLOCK_RATE},
+	}
+}
+
+func (participant *participantRecording) timestamp(at time.Time) int64 {
+	return int64(at.Sub(participant.start) / time.Millisecond)
+}
+
+// someone's screenshare is recorded separately from their camera
+type recordingKey struct {
+	userID      int
+	screenShare bool
+}
+
+/*
+MeetingRecorder is a zoom.MediaSink that records every participant into their own Matroska file with their
+H.264 video and opus audio on a common timeline. Pass the same recorder to CreateZoomVideoStreams and
+CreateZoomAudioStreams, samples are matched up by user ID. Screenshares go into files of their own.
+
+A recording starts at the first keyframe of someone's video, or after a short wait for people who only send
+audio. A new file is started when the video resolution changes or video shows up in an audio only recording.
+*/
+type MeetingRecorder struct {
+	newWriter WriterFactory
+	now       func() time.Time
+
+	// called whenever a new file is started, used by CompositeRecorder
+	segmentStarted func(segment Segment)
+
+	mu           sync.Mutex
+	participants map[recordingKey]*participantRecording
+}
+
+func NewMeetingRecorder(newWriter WriterFactory) *MeetingRecorder {
+	return &MeetingRecorder{
+		newWriter:    newWriter,
+		now:          time.Now,
+		participants: make(map[recordingKey]*participantRecording),
+	}
+}
+
+func (recorder *MeetingRecorder) OnSample(sample *zoom.MediaSample) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	key := recordingKey{userID: sample.UserID, screenShare: sample.StreamType == rtp.STREAM_TYPE_SCREENSHARE}
+	participant := recorder.participants[key]
+	if participant == nil {
+		participant = newParticipantRecording(key, -1)
+		recorder.participants[key] = participant
+	}
+
+	var err error
+	switch sample.StreamType {
+	case rtp.STREAM_TYPE_VIDEO, rtp.STREAM_TYPE_SCREENSHARE:
+		err = recorder.onVideo(participant, sample)
+	case rtp.STREAM_TYPE_AUDIO:
+		err = recorder.onAudio(participant, sample)
+	}
+	if err != nil {
+		log.Printf("Recording %v failed: %+v", sample.UserID, err)
+	}
+}
+
+func (recorder *MeetingRecorder) onVideo(participant *participantRecording, sample *zoom.MediaSample) error {
+	at := participant.video.toTime(sample.Timestamp, recorder.now())
+
+	if sample.KeyFrame {
+		sps := h264.FindNalu(sample.Data, h264.NALU_TYPE_SPS)
+		hasVideo := participant.writer != nil && participant.writer.HasVideo()
+		spsChanged := sps != nil && !bytes.Equal(sps, participant.sps)
+		if !hasVideo || spsChanged || participant.rotateAtKeyFrame {
+			track, err := NewVideoTrack(sample.Data)
+			if err != nil && !hasVideo {
+				return err
+			}
+			if err == nil {
+				err = recorder.startSegment(participant, at, track)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if participant.writer == nil || !participant.writer.HasVideo() {
+		// nothing to decode before the first keyframe
+		return nil
+	}
+	return participant.writer.WriteVideo(participant.timestamp(at), sample.KeyFrame, sample.Data)
+}
+
+func (recorder *MeetingRecorder) onAudio(participant *participantRecording, sample *zoom.MediaSample) error {
+	at := participant.audio.toTime(sample.Timestamp, recorder.now())
+	if participant.audioTrack == nil {
+		participant.audioTrack = NewAudioTrack(sample.Data)
+	}
+
+	if participant.writer == nil {
+		participant.pendingAudio = append(participant.pendingAudio, pendingAudio{at: at, packet: sample.Data})
+		if at.Sub(participant.pendingAudio[0].at) < waitForVideo {
+			return nil
+		}
+		// no video, no point in waiting any longer
+		return recorder.startSegment(participant, at, nil)
+	}
+
+	if !participant.writer.HasAudio() {
+		participant.rotateAtKeyFrame = true
+		return nil
+	}
+	return participant.writer.WriteAudio(participant.timestamp(at), sample.Data)
+}
+
+// startSegment closes the current recording of a participant and opens the next one
+func (recorder *MeetingRecorder) startSegment(participant *participantRecording, at time.Time, video *VideoTrack) error {
+	err := participant.close()
+	if err != nil {
+		log.Printf("Closing recording of %v failed: %+v", participant.userID, err)
+	}
+
+	// keep the audio that came in while we were waiting for video
+	start := at
+	if len(participant.pendingAudio) > 0 && participant.pendingAudio[0].at.Before(at) {
+		start = participant.pendingAudio[0].at
+	}
+
+	participant.segment++
+	segment := Segment{
+		UserID:      participant.userID,
+		ScreenShare: participant.screenShare,
+		Index:       participant.segment,
+		Start:       start,
+		HasVideo:    video != nil,
+		HasAudio:    participant.audioTrack != nil,
+	}
+	file, err := recorder.newWriter(segment)
+	if err != nil {
+		return err
+	}
+	writer, err := NewMatroskaWriter(file, video, participant.audioTrack)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	participant.file = file
+	participant.writer = writer
+	participant.start = start
+	participant.rotateAtKeyFrame = false
+	participant.sps = nil
+	if video != nil {
+		participant.sps = video.SPS
+	}
+	if recorder.segmentStarted != nil {
+		recorder.segmentStarted(segment)
+	}
+
+	pending := participant.pendingAudio
+	participant.pendingAudio = nil
+	if writer.HasAudio() {
+		for _, audio := range pending {
+			err := writer.WriteAudio(participant.timestamp(audio.at), audio.packet)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (participant *participantRecording) close() error {
+	if participant.file == nil {
+		return nil
+	}
+	err := participant.file.Close()
+	participant.file = nil
+	participant.writer = nil
+	return err
+}
+
+func (recorder *MeetingRecorder) OnError(err error) {
+	log.Printf("Media error: %+v", err)
+}
+
+// CloseParticipant finishes the recordings of someone who left, they get new files if they come back
+func (recorder *MeetingRecorder) CloseParticipant(userID int) error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	var firstErr error
+	for key, participant := range recorder.participants {
+		if key.userID != userID {
+			continue
+		}
+		// start over but don't overwrite the earlier recordings
+		recorder.participants[key] = newParticipantRecording(key, participant.segment)
+		err := participant.close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (recorder *MeetingRecorder) Close() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	var firstErr error
+	for key, participant := range recorder.participants {
+		err := participant.close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(recorder.participants, key)
+	}
+	return firstErr
+}
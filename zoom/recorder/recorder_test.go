@@ -0,0 +1,153 @@
+package recorder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (buffer *bufferCloser) Close() error {
+	return nil
+}
+
+type simpleBlock struct {
+	track     byte
+	timestamp int64
+	keyFrame  bool
+}
+
+type parsedRecording struct {
+	codecs []string
+	blocks []simpleBlock
+}
+
+func readVint(data []byte) (uint64, int) {
+	length := 1
+	for length <= 8 && data[0]&(0x80>>uint(length-1)) == 0 {
+		length++
+	}
+	value := uint64(data[0] & (0xFF >> uint(length)))
+	for i := 1; i < length; i++ {
+		value = value<<8 | uint64(data[i])
+	}
+	return value, length
+}
+
+// parseRecording walks the elements of a recording, master elements are entered rather than skipped
+func parseRecording(t *testing.T, data []byte) *parsedRecording {
+	masters := map[uint64]bool{
+		MKV_ID_SEGMENT & 0x0FFFFFFF: true,
+		MKV_ID_TRACKS & 0x0FFFFFFF:  true,
+		MKV_ID_TRACK_ENTRY & 0x7F:   true,
+		MKV_ID_CLUSTER & 0x0FFFFFFF: true,
+	}
+	recording := &parsedRecording{}
+	clusterTimecode := int64(0)
+	for pos := 0; pos < len(data); {
+		id, idLength := readVint(data[pos:])
+		size, sizeLength := readVint(data[pos+idLength:])
+		pos += idLength + sizeLength
+		if masters[id] {
+			continue
+		}
+		if pos+int(size) > len(data) {
+			t.Fatalf("element %x overruns the recording", id)
+		}
+		payload := data[pos : pos+int(size)]
+		switch id {
+		case MKV_ID_CODEC_ID & 0x7F:
+			recording.codecs = append(recording.codecs, string(payload))
+		case MKV_ID_TIMECODE & 0x7F:
+			clusterTimecode = 0
+			for _, b := range payload {
+				clusterTimecode = clusterTimecode<<8 | int64(b)
+			}
+		case MKV_ID_SIMPLE_BLOCK & 0x7F:
+			relative := int64(int16(uint16(payload[1])<<8 | uint16(payload[2])))
+			recording.blocks = append(recording.blocks, simpleBlock{
+				track:     payload[0] & 0x7F,
+				timestamp: clusterTimecode + relative,
+				keyFrame:  payload[3]&0x80 != 0,
+			})
+		}
+		pos += int(size)
+	}
+	return recording
+}
+
+func TestMeetingRecorder(t *testing.T) {
+	files := make(map[string]*bufferCloser)
+	recorder := NewMeetingRecorder(func(segment Segment) (io.WriteCloser, error) {
+		file := &bufferCloser{}
+		files[segment.Name()] = file
+		return file, nil
+	})
+	now := time.Unix(1600000000, 0)
+	recorder.now = func() time.Time {
+		return now
+	}
+
+	// baseline 640x360
+	sps := []byte{0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1e, 0xda, 0x02, 0x80, 0xbf, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x58, 0xba, 0x80}
+	pps := []byte{0, 0, 0, 1, 0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0, 0, 0, 1, 0x65, 0x88, 0x84}
+	slice := []byte{0, 0, 0, 1, 0x41, 0x9a, 0x02}
+	opus := []byte{0xF8, 0xFF, 0xFE}
+
+	sample := func(userID int, streamType rtp.StreamType, timestamp uint32, keyFrame bool, data ...[]byte) {
+		recorder.OnSample(&zoom.MediaSample{
+			UserID:     userID,
+			StreamType: streamType,
+			Timestamp:  timestamp,
+			KeyFrame:   keyFrame,
+			Data:       bytes.Join(data, nil),
+		})
+	}
+
+	// 1 talks with video on, the audio starts a bit before the first keyframe
+	sample(1, rtp.STREAM_TYPE_AUDIO, 1000, true, opus)
+	now = now.Add(100 * time.Millisecond)
+	sample(1, rtp.STREAM_TYPE_VIDEO, 5000, true, sps, pps, idr)
+	sample(1, rtp.STREAM_TYPE_AUDIO, 1000+960*5, true, opus)
+	now = now.Add(50 * time.Millisecond)
+	sample(1, rtp.STREAM_TYPE_VIDEO, 5000+3000, false, slice)
+
+	// 2 only talks
+	for i := uint32(0); i < 120; i++ {
+		sample(2, rtp.STREAM_TYPE_AUDIO, 960*i, true, opus)
+		now = now.Add(20 * time.Millisecond)
+	}
+	recorder.Close()
+
+	if len(files) != 2 {
+		t.Errorf("expected 2 recordings, got %v", len(files))
+		return
+	}
+
+	first := parseRecording(t, files["1-0.mkv"].Bytes())
+	if len(first.codecs) != 2 || first.codecs[0] != "V_MPEG4/ISO/AVC" || first.codecs[1] != "A_OPUS" {
+		t.Errorf("unexpected tracks %v", first.codecs)
+	}
+	expected := []simpleBlock{{2, 0, true}, {1, 100, true}, {2, 100, true}, {1, 133, false}}
+	if fmt.Sprint(first.blocks) != fmt.Sprint(expected) {
+		t.Errorf("expected blocks %v, got %v", expected, first.blocks)
+	}
+
+	second := parseRecording(t, files["2-0.mkv"].Bytes())
+	if len(second.codecs) != 1 || second.codecs[0] != "A_OPUS" {
+		t.Errorf("unexpected tracks %v", second.codecs)
+	}
+	last := second.blocks[len(second.blocks)-1]
+	if len(second.blocks) != 120 || last.timestamp != 119*20 {
+		t.Errorf("expected 120 blocks over 2.38s, got %v ending at %v", len(second.blocks), last.timestamp)
+	}
+}
@@ -1,12 +1,17 @@
 package zoom
 
+import (
+	"context"
+	"errors"
+)
+
 func (session *ZoomSession) SendChatMessage(destNodeID int, text string) error {
 	sendBody := ConferenceChatRequest{
 		DestNodeID: destNodeID,
 		Sn:         []byte(session.JoinInfo.ZoomID),
 		Text:       []byte(text),
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_CHAT_REQ, sendBody)
+	err := session.SendMessage(WS_CONF_CHAT_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -15,21 +20,34 @@ func (session *ZoomSession) SendChatMessage(destNodeID int, text string) error {
 }
 
 // host required
-func (session *ZoomSession) RequestBreakoutRoomToken(topic string, index int) error {
+// returns the bID of the new breakout room
+func (session *ZoomSession) RequestBreakoutRoomToken(ctx context.Context, topic string, index int) (string, error) {
+	err := session.requireCoHost(WS_CONF_BO_TOKEN_BATCH_REQ)
+	if err != nil {
+		return "", err
+	}
 	sendBody := ConferenceBreakoutRoomTokenBatchRequest{
 		Topic: topic,
 		Index: index,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_BO_TOKEN_BATCH_REQ, sendBody)
+	response, err := session.Request(ctx, WS_CONF_BO_TOKEN_BATCH_REQ, sendBody)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+	tokenResponse, ok := response.(*ConferenceBreakoutRoomTokenResponse)
+	if !ok {
+		return "", errors.New("unexpected response to WS_CONF_BO_TOKEN_BATCH_REQ")
+	}
+	return tokenResponse.Bid, nil
 }
 
 // host required
-// request room bIDs using session.RequestBreakoutRoomToken, store them somewhere, then use those to make the rooms.  see struct details in message_types.go
+// request room bIDs using session.RequestBreakoutRoomToken, store them somewhere, then use those to make the rooms (BreakoutManager.CreateRooms does all of this).  see struct details in message_types.go
 func (session *ZoomSession) CreateBreakoutRoom(rooms []BreakoutRoomItem, autoJoin bool, timerEnabled bool, timerDurationSeconds int, forceLeaveWait int) error {
+	err := session.requireCoHost(WS_CONF_BO_START_REQ)
+	if err != nil {
+		return err
+	}
 	protoData := ConferenceBreakoutRoomAttributeIndicationData{
 		ControlStatus:     2,
 		NameIndex:         1,
@@ -38,16 +56,15 @@ func (session *ZoomSession) CreateBreakoutRoom(rooms []BreakoutRoomItem, autoJoi
 		TimerDuration:     timerDurationSeconds,
 		// how long before people are forced to leave
 		WaitSeconds: forceLeaveWait,
-		// ???
-		StartTimeOnMMR: 464,
-		ItemList:       rooms,
+		// StartTimeOnMMR is filled in by zoom, we get it back in the WS_CONF_BO_ATTRIBUTE_INDICATION
+		ItemList: rooms,
 	}
 
 	sendBody := ConferenceBreakoutRoomStartRequest{
 		Proto: ConferenceBreakoutRoomAttributeIndicationDataAlias(protoData),
 	}
 
-	err := session.SendMessage(session.websocketConnection, WS_CONF_BO_START_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_BO_START_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -56,10 +73,95 @@ func (session *ZoomSession) CreateBreakoutRoom(rooms []BreakoutRoomItem, autoJoi
 
 // host required
 func (session *ZoomSession) BreakoutRoomBroadcast(text string) error {
+	err := session.requireCoHost(WS_CONF_BO_BROADCAST_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := ConferenceBreakoutRoomBroadcastRequest{
 		TextContent: []byte(text),
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_BO_BROADCAST_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_BO_BROADCAST_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// stops all breakout rooms, people get WaitSeconds to return to the main meeting
+func (session *ZoomSession) StopBreakoutRooms() error {
+	err := session.requireCoHost(WS_CONF_BO_STOP_REQ)
+	if err != nil {
+		return err
+	}
+	err = session.SendMessage(WS_CONF_BO_STOP_REQ, ConferenceBreakoutRoomStopRequest{})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// assigns someone who is not in a breakout room yet to a room that has been started
+func (session *ZoomSession) AssignBreakoutRoom(userID int, targetBID string) error {
+	err := session.requireCoHost(WS_CONF_BO_ASSIGN_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceBreakoutRoomAssignRequest{
+		TargetID:  userID,
+		TargetBID: targetBID,
+	}
+	err = session.SendMessage(WS_CONF_BO_ASSIGN_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// moves someone who is already in a breakout room to another one
+func (session *ZoomSession) SwitchBreakoutRoom(userID int, targetBID string) error {
+	err := session.requireCoHost(WS_CONF_BO_SWITCH_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceBreakoutRoomSwitchRequest{
+		TargetID:  userID,
+		TargetBID: targetBID,
+	}
+	err = session.SendMessage(WS_CONF_BO_SWITCH_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// assigns people by their zoom ID before the rooms are started
+func (session *ZoomSession) PreAssignBreakoutRoom(ctx context.Context, targetBID string, zoomIDs []string) error {
+	err := session.requireCoHost(WS_CONF_BO_PRE_ASSIGN_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceBreakoutRoomPreAssignRequest{
+		TargetBID:       targetBID,
+		ParticipantList: zoomIDs,
+	}
+	_, err = session.Request(ctx, WS_CONF_BO_PRE_ASSIGN_REQ, sendBody)
+	return err
+}
+
+// asks the host to come to the breakout room we are in
+func (session *ZoomSession) RequestBreakoutRoomHelp() error {
+	bid := session.BreakoutRoom()
+	if bid == "" {
+		return ErrNotInBreakoutRoom
+	}
+	sendBody := ConferenceBreakoutRoomHelpRequest{
+		Bid: bid,
+	}
+	err := session.SendMessage(WS_CONF_BO_HELP_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -67,19 +169,22 @@ func (session *ZoomSession) BreakoutRoomBroadcast(text string) error {
 }
 
 /*
-breakout room joining is not fully implemented
-you have to send the WS_CONF_BO_JOIN_REQ (which this function does), wait for the WS_CONF_BO_JOIN_RES, then make a separate websocket connection using the token you get
-breakout rooms are basically meetings= within meetings
+this sends the WS_CONF_BO_JOIN_REQ and waits for the WS_CONF_BO_JOIN_RES, the token in the response is the "opt" of the breakout room
+breakout rooms are basically meetings within meetings, use JoinBreakoutRoom to actually move there
 */
-func (session *ZoomSession) RequestBreakoutRoomJoinToken(targetBID string) error {
+func (session *ZoomSession) RequestBreakoutRoomJoinToken(ctx context.Context, targetBID string) (*ConferenceBreakoutRoomJoinResponse, error) {
 	sendBody := ConferenceBreakoutRoomJoinRequest{
 		TargetBID: targetBID,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_BO_JOIN_REQ, sendBody)
+	response, err := session.Request(ctx, WS_CONF_BO_JOIN_REQ, sendBody)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	joinResponse, ok := response.(*ConferenceBreakoutRoomJoinResponse)
+	if !ok {
+		return nil, errors.New("unexpected response to WS_CONF_BO_JOIN_REQ")
+	}
+	return joinResponse, nil
 }
 
 // equivalent to zoom "join audio" - basically just allows us to have the voice icon next to our name
@@ -89,7 +194,7 @@ func (session *ZoomSession) JoinAudioVoipChannel(status bool) error {
 		// ID:  &session.JoinInfo.UserID,
 		BOn: &status,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_AUDIO_VOIP_JOIN_CHANNEL_REQ, sendBody)
+	err := session.SendMessage(WS_AUDIO_VOIP_JOIN_CHANNEL_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -102,7 +207,7 @@ func (session *ZoomSession) SignalAudioStatus(oldAudioConnectionStatus, audioCon
 		OldAudioConnectionStatus: oldAudioConnectionStatus,
 		AudioConnectionStatus:    audioConnectionStatus,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_AUDIO_VOIP_JOIN_CHANNEL_REQ, sendBody)
+	err := session.SendMessage(WS_AUDIO_VOIP_JOIN_CHANNEL_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -117,28 +222,28 @@ func (session *ZoomSession) MuteUser(userId int, status bool) error {
 		ID:  userId,
 		BOn: status,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_VIDEO_MUTE_VIDEO_REQ, sendBody)
+	err := session.SendMessage(WS_VIDEO_MUTE_VIDEO_REQ, sendBody)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// NOTE: this does not actually allow you to screenshare, that has yet to implemented.  it just changes the indicator next to your name and will show that you have solid black video
+// NOTE: this only changes the indicator next to your name and will show that you have solid black video, use ZoomStreams.PublishVideo to actually send video
 // true for mute, false for unmute
 func (session *ZoomSession) SetVideoMuted(status bool) error {
 	sendBody := VideoMuteRequest{
 		ID:  session.JoinInfo.UserID,
 		BOn: status,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_VIDEO_MUTE_VIDEO_REQ, sendBody)
+	err := session.SendMessage(WS_VIDEO_MUTE_VIDEO_REQ, sendBody)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// NOTE: this does not actually allow you to screenshare, that has yet to implemented.  it will show that you are sharing your screen but the output will be black
+// NOTE: this only announces the screenshare, use ZoomStreams.StartScreenShare to actually send one.
 // true for mute, false for unmute
 func (session *ZoomSession) SetShareStatus(status bool, shareAudio bool) error {
 	sendBody := ConferenceSetShareStatusRequest{
@@ -148,14 +253,14 @@ func (session *ZoomSession) SetShareStatus(status bool, shareAudio bool) error {
 		},
 		BShareAudio: shareAudio,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_SET_SHARE_STATUS_REQ, sendBody)
+	err := session.SendMessage(WS_CONF_SET_SHARE_STATUS_REQ, sendBody)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// NOTE: this does not actually allow you to output any audio, that has yet to implemented.  it just changes the indicator next to your username.
+// NOTE: this only changes the indicator next to your username, use ZoomStreams.PublishAudio to actually output audio.
 // true for mute, false for unmute
 func (session *ZoomSession) SetAudioMuted(status bool) error {
 	// need to be into voip channel to be able to mute ourselves
@@ -167,7 +272,7 @@ func (session *ZoomSession) SetAudioMuted(status bool) error {
 	sendBody := AudioMuteRequest{
 		BMute: status,
 	}
-	err = session.SendMessage(session.websocketConnection, WS_AUDIO_MUTE_REQ, sendBody)
+	err = session.SendMessage(WS_AUDIO_MUTE_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -180,12 +285,18 @@ func (session *ZoomSession) RenameMe(newName string) error {
 
 // host required to rename others (not self)
 func (session *ZoomSession) RenameById(id int, oldName string, newName string) error {
+	if session.JoinInfo == nil || id != session.JoinInfo.UserID {
+		err := session.requireCoHost(WS_CONF_RENAME_REQ)
+		if err != nil {
+			return err
+		}
+	}
 	sendBody := ConferenceRenameRequest{
 		ID:     id,
 		Dn2:    []byte(newName),
 		Olddn2: []byte(oldName),
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_RENAME_REQ, sendBody)
+	err := session.SendMessage(WS_CONF_RENAME_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -197,10 +308,32 @@ func (session *ZoomSession) RenameById(id int, oldName string, newName string) e
 
 // host required
 func (session *ZoomSession) RequestAllMute() error {
+	err := session.requireCoHost(WS_AUDIO_MUTEALL_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := AudioMuteAllRequest{
 		BMute: true,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_AUDIO_MUTEALL_REQ, sendBody)
+	err = session.SendMessage(WS_AUDIO_MUTEALL_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// zoom lets the host mute others but only ask them to unmute
+func (session *ZoomSession) MuteParticipantAudio(userID int) error {
+	err := session.requireCoHost(WS_AUDIO_MUTE_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := AudioMuteRequest{
+		BMute: true,
+		ID:    userID,
+	}
+	err = session.SendMessage(WS_AUDIO_MUTE_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -212,7 +345,7 @@ func (session *ZoomSession) RaiseHand(id int, shouldRaise bool) error {
 		BOn: shouldRaise,
 		ID:  id,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_RAISE_LOWER_HAND_REQ, sendBody)
+	err := session.SendMessage(WS_CONF_RAISE_LOWER_HAND_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -221,10 +354,14 @@ func (session *ZoomSession) RaiseHand(id int, shouldRaise bool) error {
 
 // host required
 func (session *ZoomSession) SetMuteUponEntry(status bool) error {
+	err := session.requireCoHost(WS_CONF_SET_MUTE_UPON_ENTRY_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := ConferenceSetMuteUponEntryRequest{
 		BOn: status,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_SET_MUTE_UPON_ENTRY_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_SET_MUTE_UPON_ENTRY_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -233,10 +370,14 @@ func (session *ZoomSession) SetMuteUponEntry(status bool) error {
 
 // host required
 func (session *ZoomSession) SetAllowUnmuteAudio(status bool) error {
+	err := session.requireCoHost(WS_CONF_ALLOW_UNMUTE_AUDIO_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := ConferenceAllowUnmuteAudioRequest{
 		BOn: true,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_ALLOW_UNMUTE_AUDIO_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_ALLOW_UNMUTE_AUDIO_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -245,10 +386,14 @@ func (session *ZoomSession) SetAllowUnmuteAudio(status bool) error {
 
 // host required
 func (session *ZoomSession) SetAllowParticipantRename(status bool) error {
+	err := session.requireCoHost(WS_CONF_ALLOW_PARTICIPANT_RENAME_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := ConferenceAllowParticipantRenameRequest{
 		BOn: true,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_ALLOW_PARTICIPANT_RENAME_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_ALLOW_PARTICIPANT_RENAME_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -257,10 +402,68 @@ func (session *ZoomSession) SetAllowParticipantRename(status bool) error {
 
 // host required
 func (session *ZoomSession) SetAllowUnmuteVideo(status bool) error {
+	err := session.requireCoHost(WS_CONF_ALLOW_UNMUTE_VIDEO_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := ConferenceAllowUnmuteVideoRequest{
 		BOn: true,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_ALLOW_UNMUTE_VIDEO_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_ALLOW_UNMUTE_VIDEO_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// hold sends someone to the waiting room, not holding them admits them
+func (session *ZoomSession) PutOnHold(userID int, hold bool) error {
+	err := session.requireCoHost(WS_CONF_PUT_ON_HOLD_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferencePutOnHoldRequest{
+		BHold: hold,
+		ID:    userID,
+	}
+	err = session.SendMessage(WS_CONF_PUT_ON_HOLD_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+func (session *ZoomSession) AdmitFromWaitingRoom(userID int) error {
+	return session.PutOnHold(userID, false)
+}
+
+// host required
+func (session *ZoomSession) AdmitAllFromWaitingRoom() error {
+	err := session.requireCoHost(WS_CONF_ADMIT_ALL_SILENT_USERS_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceAdmitAllSilentUsersRequest{}
+	err = session.SendMessage(WS_CONF_ADMIT_ALL_SILENT_USERS_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// when on, everyone who joins ends up in the waiting room first
+func (session *ZoomSession) SetHoldUponEntry(status bool) error {
+	err := session.requireCoHost(WS_CONF_SET_HOLD_UPON_ENTRY_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceSetHoldUponEntryRequest{
+		BOn: status,
+	}
+	err = session.SendMessage(WS_CONF_SET_HOLD_UPON_ENTRY_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -270,10 +473,14 @@ func (session *ZoomSession) SetAllowUnmuteVideo(status bool) error {
 // host required
 // possible values: CHAT_EVERYONE_PUBLICLY_PRIVATELY = 1, CHAT_HOST_ONLY = 3, CHAT_NO_ONE = 4, CHAT_EVERYONE_PUBLICLY = 5
 func (session *ZoomSession) SetChatLevel(status int) error {
+	err := session.requireCoHost(WS_CONF_CHAT_PRIVILEDGE_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := ConferenceChatPrivilegeRequest{
 		ChatPriviledge: status,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_CHAT_PRIVILEDGE_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_CHAT_PRIVILEDGE_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -292,10 +499,14 @@ CMM_SHARE_SETTING_ANYONE_GRAB = 2 (How many participants can share at the same t
 CMM_SHARE_SETTING_MULTI_SHARE = 3 (How many participants can share at the same time? Multiple participants can share simultaneously)
 */
 func (session *ZoomSession) SetShareLockedStatus(status int) error {
+	err := session.requireCoHost(WS_CONF_LOCK_SHARE_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := ConferenceLockShareRequest{
 		LockShare: status,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_LOCK_SHARE_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_LOCK_SHARE_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -307,7 +518,19 @@ func (session *ZoomSession) SharingSubscribeRequest(id int, size int) error {
 		ID:   id,
 		Size: size,
 	}
-	err := session.SendMessage(session.websocketConnection, WS_SHARING_SUBSCRIBE_REQ, sendBody)
+	err := session.SendMessage(WS_SHARING_SUBSCRIBE_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RequestKeyFrame asks whoever sends the video with this ssrc for a keyframe (untested)
+func (session *ZoomSession) RequestKeyFrame(ssrc int) error {
+	sendBody := VideoKeyFrameRequest{
+		SSRC: ssrc,
+	}
+	err := session.SendMessage(WS_VIDEO_KEY_FRAME_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -323,7 +546,7 @@ func (session *ZoomSession) VideoSubscribeRequest(id int, size int) error {
 	sendBody := VideoSubscribeRequest{
 		SubInfoList: []VideoSubInfo{sub},
 	}
-	err := session.SendMessage(session.websocketConnection, WS_VIDEO_MULTI_SUBSCRIBE_REQ, sendBody)
+	err := session.SendMessage(WS_VIDEO_MULTI_SUBSCRIBE_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -337,7 +560,164 @@ func (session *ZoomSession) VideoUnsubscribeRequest(id int) error {
 	sendBody := VideoUnsubscribeRequest{
 		SubIDList: []VideoSubID{sub},
 	}
-	err := session.SendMessage(session.websocketConnection, WS_VIDEO_MULTI_UNSUBSCRIBE_REQ, sendBody)
+	err := session.SendMessage(WS_VIDEO_MULTI_UNSUBSCRIBE_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// waits for zoom to confirm, a *ResponseError means zoom refused
+func (session *ZoomSession) ExpelParticipant(ctx context.Context, userID int) error {
+	err := session.requireCoHost(WS_CONF_EXPEL_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceExpelRequest{
+		ID: userID,
+	}
+	_, err = session.Request(ctx, WS_CONF_EXPEL_REQ, sendBody)
+	return err
+}
+
+// host required
+// for attendees of a webinar, use ExpelParticipant for everyone else
+func (session *ZoomSession) ExpelWebinarAttendee(ctx context.Context, userID int) error {
+	err := session.requireCoHost(WS_CONF_EXPEL_ATTENDEE_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceExpelAttendeeRequest{
+		ID: userID,
+	}
+	_, err = session.Request(ctx, WS_CONF_EXPEL_ATTENDEE_REQ, sendBody)
+	return err
+}
+
+// host required
+// nobody new can join a locked meeting, waits for zoom to confirm
+func (session *ZoomSession) LockMeeting(ctx context.Context) error {
+	return session.setMeetingLocked(ctx, true)
+}
+
+// host required
+func (session *ZoomSession) UnlockMeeting(ctx context.Context) error {
+	return session.setMeetingLocked(ctx, false)
+}
+
+func (session *ZoomSession) setMeetingLocked(ctx context.Context, locked bool) error {
+	err := session.requireCoHost(WS_CONF_LOCK_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceLockRequest{
+		BLock: locked,
+	}
+	_, err = session.Request(ctx, WS_CONF_LOCK_REQ, sendBody)
+	return err
+}
+
+// host required
+// stops the screenshare of someone else (untested)
+func (session *ZoomSession) StopSharing(userID int) error {
+	err := session.requireCoHost(WS_CONF_SET_SHARE_STATUS_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceSetShareStatusRequest{
+		BOnRequest: BOnRequest{
+			ID:  userID,
+			BOn: false,
+		},
+	}
+	err = session.SendMessage(WS_CONF_SET_SHARE_STATUS_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// makes us host with the host key of the meeting owner, waits for zoom to accept the key
+func (session *ZoomSession) ClaimHostWithKey(ctx context.Context, key string) error {
+	sendBody := ConferenceHostKeyRequest{
+		HostKey: key,
+	}
+	_, err := session.Request(ctx, WS_CONF_HOST_KEY_REQ, sendBody)
+	return err
+}
+
+// takes host back, only works for the owner of the meeting
+func (session *ZoomSession) ReclaimHost() error {
+	err := session.SendMessage(WS_CONF_RECLAIM_HOST_REQ, ConferenceReclaimHostRequest{})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// hands host over to someone else, we become an attendee
+func (session *ZoomSession) AssignHost(userID int) error {
+	err := session.requireHost(WS_CONF_ASSIGN_HOST_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceAssignHostRequest{
+		ID: userID,
+	}
+	err = session.SendMessage(WS_CONF_ASSIGN_HOST_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+func (session *ZoomSession) MakeCoHost(userID int) error {
+	err := session.requireHost(WS_CONF_ASSIGN_HOST_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceAssignHostRequest{
+		ID:      userID,
+		BCoHost: true,
+	}
+	err = session.SendMessage(WS_CONF_ASSIGN_HOST_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+func (session *ZoomSession) RevokeCoHost(userID int) error {
+	err := session.requireHost(WS_CONF_REVOKE_COHOST_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceRevokeCoHostRequest{
+		ID: userID,
+	}
+	err = session.SendMessage(WS_CONF_REVOKE_COHOST_REQ, sendBody)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// host required
+// lets someone type closed captions, use NewCaptionPublisher to send them ourselves
+func (session *ZoomSession) AssignCaptioner(userID int, status bool) error {
+	err := session.requireHost(WS_CONF_ASSIGN_CC_REQ)
+	if err != nil {
+		return err
+	}
+	sendBody := ConferenceAssignCaptionerRequest{
+		ID:        userID,
+		BCCEditor: status,
+	}
+	err = session.SendMessage(WS_CONF_ASSIGN_CC_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -346,8 +726,12 @@ func (session *ZoomSession) VideoUnsubscribeRequest(id int) error {
 
 // host required
 func (session *ZoomSession) EndMeeting() error {
+	err := session.requireHost(WS_CONF_END_REQ)
+	if err != nil {
+		return err
+	}
 	sendBody := ConferenceEndRequest{}
-	err := session.SendMessage(session.websocketConnection, WS_CONF_END_REQ, sendBody)
+	err = session.SendMessage(WS_CONF_END_REQ, sendBody)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,64 @@
+package zoom
+
+import "fmt"
+
+/*
+PermissionError is returned straight away by requests that need a role the session does not have, so they
+fail before zoom silently ignores them. It is only returned once the session has seen itself in the roster,
+until then requests are sent and zoom decides.
+*/
+type PermissionError struct {
+	Evt int
+	// "host" or "co-host", co-host requests can be made by the host as well
+	Required string
+}
+
+func (err *PermissionError) Error() string {
+	return fmt.Sprintf("%s requires the session to be %s", MessageNumberToName[err.Evt], err.Required)
+}
+
+// self returns what the roster knows about us
+func (session *ZoomSession) self() (Participant, bool) {
+	if session.JoinInfo == nil || session.Roster == nil {
+		return Participant{}, false
+	}
+	return session.Roster.Get(session.JoinInfo.UserID)
+}
+
+// IsHost returns whether the session is the host of the meeting, false if the roster did not tell us yet
+func (session *ZoomSession) IsHost() bool {
+	self, ok := session.self()
+	return ok && self.IsHost()
+}
+
+// IsCoHost returns whether the session is co-host, the host is not a co-host
+func (session *ZoomSession) IsCoHost() bool {
+	self, ok := session.self()
+	return ok && self.IsCoHost
+}
+
+func (session *ZoomSession) requireHost(evt int) error {
+	self, ok := session.self()
+	if ok && !self.IsHost() {
+		return &PermissionError{Evt: evt, Required: "host"}
+	}
+	return nil
+}
+
+func (session *ZoomSession) requireCoHost(evt int) error {
+	self, ok := session.self()
+	if ok && !self.IsHost() && !self.IsCoHost {
+		return &PermissionError{Evt: evt, Required: "co-host"}
+	}
+	return nil
+}
+
+// zoom tells us directly when we are made co-host, the roster update for ourselves may come later
+func (session *ZoomSession) updateCoHost(indication *ConferenceCohostChangeIndication) {
+	if session.JoinInfo == nil {
+		return
+	}
+	for _, event := range session.Roster.setCoHost(session.JoinInfo.UserID, indication.BCoHost) {
+		session.emit(event.evt, event.message)
+	}
+}
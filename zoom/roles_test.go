@@ -0,0 +1,50 @@
+package zoom
+
+import (
+	"testing"
+)
+
+func TestRequestsFailFastWithoutRole(t *testing.T) {
+	session := &ZoomSession{
+		JoinInfo: &JoinConferenceResponse{UserID: 16778240},
+		Roster:   NewRoster(),
+		events:   newEventBus(),
+	}
+
+	// until we are in the roster zoom gets to decide
+	if session.requireCoHost(WS_AUDIO_MUTE_REQ) != nil {
+		t.Error("did not expect an error before we know our role")
+	}
+
+	applyRosterJSON(t, session.Roster, `{"add":[{"id":16778240,"dn2":"Qm90"}],"remove":null,"update":null}`)
+	err := session.MuteParticipantAudio(16779264)
+	permissionErr, ok := err.(*PermissionError)
+	if !ok || permissionErr.Evt != WS_AUDIO_MUTE_REQ || permissionErr.Required != "co-host" {
+		t.Errorf("expected a PermissionError for co-host, got %v", err)
+	}
+
+	changes := make([]*RoleChanged, 0)
+	session.On(LOCAL_PARTICIPANT_ROLE_CHANGED, func(changed *RoleChanged) {
+		changes = append(changes, changed)
+	})
+	session.updateCoHost(&ConferenceCohostChangeIndication{BCoHost: true})
+	if !session.IsCoHost() || session.IsHost() {
+		t.Error("expected to be co-host")
+	}
+	if len(changes) != 1 || changes[0].WasCoHost {
+		t.Errorf("expected a role change, got %v", changes)
+	}
+	if _, ok := session.AssignHost(16779264).(*PermissionError); !ok {
+		t.Error("expected co-hosts to be unable to assign host")
+	}
+
+	// handing host back and forth shows up as role changes in the roster
+	events := applyRosterJSON(t, session.Roster, `{"add":null,"remove":null,"update":[{"id":16778240,"role":1,"bCoHost":false}]}`)
+	if len(events) != 1 || events[0].evt != LOCAL_PARTICIPANT_ROLE_CHANGED || !session.IsHost() {
+		t.Errorf("expected to become host, got %v", events)
+	}
+	events = applyRosterJSON(t, session.Roster, `{"add":null,"remove":null,"update":[{"id":16778240,"role":0}]}`)
+	if len(events) != 1 || session.IsHost() {
+		t.Errorf("expected to lose host, got %v", events)
+	}
+}
@@ -0,0 +1,350 @@
+package zoom
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// as far as we can tell zoom only ever sends these values for the role of a participant
+const (
+	USER_ROLE_ATTENDEE = 0
+	USER_ROLE_HOST     = 1
+)
+
+// after reconnecting, zoom sends large rosters in chunks. Once it has been quiet for this long we take it that
+// everyone still in the meeting was sent to us again.
+const ROSTER_SETTLE_TIME = 3 * time.Second
+
+// Participant is a copy of what we know about someone in the meeting, it does not change when the roster does
+type Participant struct {
+	UserID      int
+	ZoomID      string
+	DisplayName string
+	Email       string
+	Avatar      string
+	Role        int
+	IsCoHost    bool
+	IsGuest     bool
+	IsOnHold    bool
+	// allowed to type closed captions
+	IsCaptioner bool
+	Muted       bool
+	VideoOn     bool
+	HandRaised  bool
+	// see ZoomSession.SignalAudioStatus
+	AudioConnectionStatus int
+}
+
+func (participant Participant) IsHost() bool {
+	return participant.Role == USER_ROLE_HOST
+}
+
+// ParticipantJoined is emitted when someone is added to the roster
+type ParticipantJoined struct {
+	Participant Participant
+}
+
+// ParticipantLeft is emitted when someone is removed from the roster, it contains the last known state
+type ParticipantLeft struct {
+	Participant Participant
+}
+
+type HandRaised struct {
+	Participant Participant
+}
+
+type HandLowered struct {
+	Participant Participant
+}
+
+type Renamed struct {
+	Participant Participant
+	OldName     string
+}
+
+type MuteChanged struct {
+	Participant Participant
+}
+
+type VideoChanged struct {
+	Participant Participant
+}
+
+// RoleChanged is emitted when someone becomes or stops being host or co-host
+type RoleChanged struct {
+	Participant Participant
+	OldRole     int
+	WasCoHost   bool
+}
+
+// HoldChanged is emitted when someone is admitted from the waiting room or sent back to it
+type HoldChanged struct {
+	Participant Participant
+}
+
+type rosterEvent struct {
+	evt     int
+	message Message
+}
+
+/*
+Roster is the list of participants in the meeting as described by the WS_CONF_ROSTER_INDICATION messages.
+
+Zoom only sends the fields that changed in an update, the roster merges these into the full state of every
+participant. The session keeps its roster up to date before any user defined handler sees the indication,
+so handlers can look people up in it.
+*/
+type Roster struct {
+	mu           sync.RWMutex
+	participants map[ /*userId*/ int]*Participant
+	// after reconnecting, everyone zoom has not sent us again yet. See dropStale.
+	stale        map[ /*userId*/ int]bool
+	staleUpdated time.Time
+}
+
+func NewRoster() *Roster {
+	return &Roster{
+		participants: make(map[int]*Participant),
+	}
+}
+
+// Get returns the participant with the given user ID
+func (roster *Roster) Get(userID int) (Participant, bool) {
+	roster.mu.RLock()
+	defer roster.mu.RUnlock()
+
+	participant, ok := roster.participants[userID]
+	if !ok {
+		return Participant{}, false
+	}
+	return *participant, true
+}
+
+// GetByZoomID returns the participant with the given ZoomID, this is the ID that stays the same when someone rejoins
+func (roster *Roster) GetByZoomID(zoomID string) (Participant, bool) {
+	roster.mu.RLock()
+	defer roster.mu.RUnlock()
+
+	for _, participant := range roster.participants {
+		if participant.ZoomID == zoomID {
+			return *participant, true
+		}
+	}
+	return Participant{}, false
+}
+
+// Snapshot returns everyone in the meeting ordered by user ID
+func (roster *Roster) Snapshot() []Participant {
+	roster.mu.RLock()
+	defer roster.mu.RUnlock()
+
+	snapshot := make([]Participant, 0, len(roster.participants))
+	for _, participant := range roster.sorted() {
+		snapshot = append(snapshot, *participant)
+	}
+	return snapshot
+}
+
+// WaitingRoom returns everyone in the waiting room ordered by user ID
+func (roster *Roster) WaitingRoom() []Participant {
+	waiting := make([]Participant, 0)
+	for _, participant := range roster.Snapshot() {
+		if participant.IsOnHold {
+			waiting = append(waiting, participant)
+		}
+	}
+	return waiting
+}
+
+func (roster *Roster) Len() int {
+	roster.mu.RLock()
+	defer roster.mu.RUnlock()
+	return len(roster.participants)
+}
+
+// sorted returns the participants ordered by user ID, the lock has to be held
+func (roster *Roster) sorted() []*Participant {
+	participants := make([]*Participant, 0, len(roster.participants))
+	for _, participant := range roster.participants {
+		participants = append(participants, participant)
+	}
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].UserID < participants[j].UserID
+	})
+	return participants
+}
+
+// reset forgets everyone without emitting any events, used when we move to another meeting
+func (roster *Roster) reset() {
+	roster.mu.Lock()
+	defer roster.mu.Unlock()
+	roster.participants = make(map[int]*Participant)
+	roster.stale = nil
+}
+
+// markStale is called after reconnecting, zoom sends us the full roster again but nobody tells us who left in the meantime
+func (roster *Roster) markStale() {
+	roster.mu.Lock()
+	defer roster.mu.Unlock()
+
+	roster.stale = make(map[int]bool, len(roster.participants))
+	for userID := range roster.participants {
+		roster.stale[userID] = true
+	}
+	roster.staleUpdated = time.Now()
+}
+
+/*
+dropStale removes whoever zoom did not send us again since reconnecting, once the roster has not changed for
+ROSTER_SETTLE_TIME. They left while we were gone. Until then the roster can't tell a participant that left from
+one in a chunk of the roster that is still on its way.
+*/
+func (roster *Roster) dropStale(now time.Time) []rosterEvent {
+	roster.mu.Lock()
+	defer roster.mu.Unlock()
+
+	if roster.stale == nil || now.Sub(roster.staleUpdated) < ROSTER_SETTLE_TIME {
+		return nil
+	}
+	events := make([]rosterEvent, 0)
+	for _, participant := range roster.sorted() {
+		if roster.stale[participant.UserID] {
+			delete(roster.participants, participant.UserID)
+			events = append(events, rosterEvent{LOCAL_PARTICIPANT_LEFT, &ParticipantLeft{Participant: *participant}})
+		}
+	}
+	roster.stale = nil
+	return events
+}
+
+// apply merges the indication into the roster and returns what changed
+func (roster *Roster) apply(indication *ConferenceRosterIndication) []rosterEvent {
+	roster.mu.Lock()
+	defer roster.mu.Unlock()
+
+	events := make([]rosterEvent, 0)
+
+	if roster.stale != nil && len(indication.Add) > 0 {
+		roster.staleUpdated = time.Now()
+	}
+
+	for _, person := range indication.Add {
+		delete(roster.stale, person.ID)
+		participant, exists := roster.participants[person.ID]
+		if !exists {
+			participant = &Participant{UserID: person.ID}
+			roster.participants[person.ID] = participant
+		}
+		participant.ZoomID = person.ZoomID
+		participant.DisplayName = string(person.Dn2)
+		participant.Avatar = person.Avatar
+		if person.Email != "" {
+			participant.Email = person.Email
+		}
+		participant.Role = person.Role
+		participant.IsGuest = person.BGuest
+		participant.IsOnHold = person.BHold
+		participant.IsCaptioner = person.BCCEditor
+		if person.BRaiseHand != nil {
+			participant.HandRaised = *person.BRaiseHand
+		}
+		if person.Muted != nil {
+			participant.Muted = *person.Muted
+		}
+		if person.BVideoOn != nil {
+			participant.VideoOn = *person.BVideoOn
+		}
+		if person.BCoHost != nil {
+			participant.IsCoHost = *person.BCoHost
+		}
+		if person.AudioConnectionStatus != nil {
+			participant.AudioConnectionStatus = *person.AudioConnectionStatus
+		}
+		// we also get the full roster again after reconnecting, these people did not just join
+		if !exists {
+			events = append(events, rosterEvent{LOCAL_PARTICIPANT_JOINED, &ParticipantJoined{Participant: *participant}})
+		}
+	}
+
+	for _, person := range indication.Update {
+		participant, exists := roster.participants[person.ID]
+		if !exists {
+			// updates for people we have never seen (eg. ourselves in the waiting room) are not worth tracking
+			continue
+		}
+		// zoom only sends updates for people who are still there
+		delete(roster.stale, person.ID)
+		if len(person.Dn2) > 0 && string(person.Dn2) != participant.DisplayName {
+			oldName := participant.DisplayName
+			participant.DisplayName = string(person.Dn2)
+			events = append(events, rosterEvent{LOCAL_PARTICIPANT_RENAMED, &Renamed{Participant: *participant, OldName: oldName}})
+		}
+		oldRole, wasCoHost := participant.Role, participant.IsCoHost
+		if person.Role != nil {
+			participant.Role = *person.Role
+		}
+		if person.BCoHost != nil {
+			participant.IsCoHost = *person.BCoHost
+		}
+		if participant.Role != oldRole || participant.IsCoHost != wasCoHost {
+			events = append(events, rosterEvent{LOCAL_PARTICIPANT_ROLE_CHANGED, &RoleChanged{Participant: *participant, OldRole: oldRole, WasCoHost: wasCoHost}})
+		}
+		if person.AudioConnectionStatus != nil {
+			participant.AudioConnectionStatus = *person.AudioConnectionStatus
+		}
+		if person.Muted != nil && *person.Muted != participant.Muted {
+			participant.Muted = *person.Muted
+			events = append(events, rosterEvent{LOCAL_PARTICIPANT_MUTE_CHANGED, &MuteChanged{Participant: *participant}})
+		}
+		if person.BVideoOn != nil && *person.BVideoOn != participant.VideoOn {
+			participant.VideoOn = *person.BVideoOn
+			events = append(events, rosterEvent{LOCAL_PARTICIPANT_VIDEO_CHANGED, &VideoChanged{Participant: *participant}})
+		}
+		if person.BCCEditor != nil {
+			participant.IsCaptioner = *person.BCCEditor
+		}
+		if person.BHold != nil && *person.BHold != participant.IsOnHold {
+			participant.IsOnHold = *person.BHold
+			events = append(events, rosterEvent{LOCAL_PARTICIPANT_HOLD_CHANGED, &HoldChanged{Participant: *participant}})
+		}
+		if person.BRaiseHand != nil && *person.BRaiseHand != participant.HandRaised {
+			participant.HandRaised = *person.BRaiseHand
+			if participant.HandRaised {
+				events = append(events, rosterEvent{LOCAL_PARTICIPANT_HAND_RAISED, &HandRaised{Participant: *participant}})
+			} else {
+				events = append(events, rosterEvent{LOCAL_PARTICIPANT_HAND_LOWERED, &HandLowered{Participant: *participant}})
+			}
+		}
+	}
+
+	for _, person := range indication.Remove {
+		participant, exists := roster.participants[person.ID]
+		if !exists {
+			continue
+		}
+		delete(roster.participants, person.ID)
+		events = append(events, rosterEvent{LOCAL_PARTICIPANT_LEFT, &ParticipantLeft{Participant: *participant}})
+	}
+
+	return events
+}
+
+func (roster *Roster) setCoHost(userID int, coHost bool) []rosterEvent {
+	roster.mu.Lock()
+	defer roster.mu.Unlock()
+
+	participant, exists := roster.participants[userID]
+	if !exists || participant.IsCoHost == coHost {
+		return nil
+	}
+	participant.IsCoHost = coHost
+	return []rosterEvent{{LOCAL_PARTICIPANT_ROLE_CHANGED, &RoleChanged{Participant: *participant, OldRole: participant.Role, WasCoHost: !coHost}}}
+}
+
+// updateRoster is registered as the very first handler of every session
+func (session *ZoomSession) updateRoster(indication *ConferenceRosterIndication) {
+	for _, event := range session.Roster.apply(indication) {
+		session.emit(event.evt, event.message)
+	}
+}
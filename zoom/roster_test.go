@@ -0,0 +1,117 @@
+package zoom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func applyRosterJSON(t *testing.T, roster *Roster, body string) []rosterEvent {
+	indication := &ConferenceRosterIndication{}
+	err := json.Unmarshal([]byte(body), indication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return roster.apply(indication)
+}
+
+func TestRosterJoinUpdateLeave(t *testing.T) {
+	roster := NewRoster()
+
+	// "Alice" and "Bob" base64 encoded without padding
+	events := applyRosterJSON(t, roster, `{"add":[{"id":16778240,"dn2":"QWxpY2U","zoomID":"abc","role":1},{"id":16779264,"dn2":"Qm9i"}],"remove":null,"update":null}`)
+	if len(events) != 2 || events[0].evt != LOCAL_PARTICIPANT_JOINED || events[1].evt != LOCAL_PARTICIPANT_JOINED {
+		t.Errorf("expected two joins, got %v", events)
+	}
+	alice, ok := roster.Get(16778240)
+	if !ok || alice.DisplayName != "Alice" || !alice.IsHost() {
+		t.Errorf("unexpected participant %+v", alice)
+	}
+	if _, ok := roster.GetByZoomID("abc"); !ok {
+		t.Error("expected to find Alice by ZoomID")
+	}
+
+	// an update that only contains the mute state should leave the video state alone
+	events = applyRosterJSON(t, roster, `{"add":null,"remove":null,"update":[{"id":16778240,"bVideoOn":true}]}`)
+	if len(events) != 1 || events[0].evt != LOCAL_PARTICIPANT_VIDEO_CHANGED {
+		t.Errorf("expected a video change, got %v", events)
+	}
+	events = applyRosterJSON(t, roster, `{"add":null,"remove":null,"update":[{"id":16778240,"muted":true,"bRaiseHand":true,"dn2":"QWxpY2UgMg"}]}`)
+	if len(events) != 3 || events[0].evt != LOCAL_PARTICIPANT_RENAMED || events[1].evt != LOCAL_PARTICIPANT_MUTE_CHANGED || events[2].evt != LOCAL_PARTICIPANT_HAND_RAISED {
+		t.Errorf("expected rename, mute and hand raise, got %v", events)
+	}
+	if renamed := events[0].message.(*Renamed); renamed.OldName != "Alice" || renamed.Participant.DisplayName != "Alice 2" {
+		t.Errorf("unexpected rename %+v", renamed)
+	}
+	alice, _ = roster.Get(16778240)
+	if !alice.VideoOn || !alice.Muted || !alice.HandRaised {
+		t.Errorf("unexpected participant %+v", alice)
+	}
+
+	// the full roster is sent again after reconnecting
+	events = applyRosterJSON(t, roster, `{"add":[{"id":16779264,"dn2":"Qm9i"}],"remove":null,"update":null}`)
+	if len(events) != 0 {
+		t.Errorf("expected no events for someone we already know, got %v", events)
+	}
+
+	events = applyRosterJSON(t, roster, `{"add":null,"remove":[{"id":16779264}],"update":null}`)
+	if len(events) != 1 || events[0].evt != LOCAL_PARTICIPANT_LEFT {
+		t.Errorf("expected a leave, got %v", events)
+	}
+
+	snapshot := roster.Snapshot()
+	if len(snapshot) != 1 || snapshot[0].UserID != 16778240 {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+}
+
+func TestRosterWaitingRoom(t *testing.T) {
+	roster := NewRoster()
+
+	applyRosterJSON(t, roster, `{"add":[{"id":16778240,"dn2":"QWxpY2U","bHold":true,"email":"alice@example.com"},{"id":16779264,"dn2":"Qm9i"}],"remove":null,"update":null}`)
+	waiting := roster.WaitingRoom()
+	if len(waiting) != 1 || waiting[0].UserID != 16778240 || waiting[0].Email != "alice@example.com" {
+		t.Errorf("expected Alice to be waiting, got %+v", waiting)
+	}
+
+	events := applyRosterJSON(t, roster, `{"add":null,"remove":null,"update":[{"id":16778240,"bHold":false}]}`)
+	if len(events) != 1 || events[0].evt != LOCAL_PARTICIPANT_HOLD_CHANGED {
+		t.Errorf("expected a hold change, got %v", events)
+	}
+	if len(roster.WaitingRoom()) != 0 {
+		t.Error("expected the waiting room to be empty")
+	}
+}
+
+func TestRosterReconcilesAfterReconnect(t *testing.T) {
+	roster := NewRoster()
+	applyRosterJSON(t, roster, `{"add":[{"id":16778240,"dn2":"QWxpY2U"},{"id":16779264,"dn2":"Qm9i"},{"id":16780288,"dn2":"Q2Fyb2w"}],"remove":null,"update":null}`)
+
+	// Bob left while we were reconnecting, zoom sends the roster in two chunks
+	roster.markStale()
+	events := applyRosterJSON(t, roster, `{"add":[{"id":16778240,"dn2":"QWxpY2U"}],"remove":null,"update":null}`)
+	if len(events) != 0 {
+		t.Errorf("expected nobody to leave before the roster is complete, got %v", events)
+	}
+	if events := roster.dropStale(time.Now()); len(events) != 0 {
+		t.Errorf("expected to wait for the rest of the roster, got %v", events)
+	}
+	events = applyRosterJSON(t, roster, `{"add":[{"id":16780288,"dn2":"Q2Fyb2w"}],"remove":null,"update":null}`)
+	if len(events) != 0 || roster.Len() != 3 {
+		t.Errorf("expected Carol to still be there, got %v", events)
+	}
+
+	events = roster.dropStale(time.Now().Add(ROSTER_SETTLE_TIME))
+	if len(events) != 1 || events[0].evt != LOCAL_PARTICIPANT_LEFT || events[0].message.(*ParticipantLeft).Participant.DisplayName != "Bob" {
+		t.Errorf("expected Bob to leave, got %v", events)
+	}
+	if events := roster.dropStale(time.Now().Add(ROSTER_SETTLE_TIME)); len(events) != 0 || roster.Len() != 2 {
+		t.Errorf("expected Bob to leave once, got %v", events)
+	}
+
+	// later joins are just joins
+	events = applyRosterJSON(t, roster, `{"add":[{"id":16781312,"dn2":"RGF2ZQ"}],"remove":null,"update":null}`)
+	if len(events) != 1 || events[0].evt != LOCAL_PARTICIPANT_JOINED || roster.Len() != 3 {
+		t.Errorf("expected Dave to join, got %v", events)
+	}
+}
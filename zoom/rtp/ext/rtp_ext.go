@@ -10,4 +10,7 @@ const (
 	RTP_EXTENSION_ID_VIDEO_UNKNOWN_5        = 5 // always 00
 	RTP_EXTENSION_ID_VIDEO_UNKNOWN_7        = 7 // always 00
 	RTP_EXTENSION_UNKNOWN                   = 1
+
+	// length of the ciphertext, length of the IV and the first half of the IV
+	RTP_EXTENSION_AUDIO_IV_LENGTH = 9
 )
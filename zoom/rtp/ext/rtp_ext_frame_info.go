@@ -131,7 +131,7 @@ func (ext *RtpExtFrameInfo) Marshal() ([]byte, error) {
 		pktInfo |= 0x40
 	}
 	if ext.Independent {
-		pktInfo |= 0x40
+		pktInfo |= 0x20
 	}
 	if ext.Required {
 		pktInfo |= 0x10
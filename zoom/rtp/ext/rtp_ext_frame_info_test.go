@@ -78,3 +78,36 @@ func TestMarshalRtpExtensionFrameInfo(t *testing.T) {
 		t.Error("payload did not match expected")
 	}
 }
+
+func TestMarshalRtpExtensionFrameInfoKeyFrameStart(t *testing.T) {
+	// pktInfo=184 curFrame=2569 prevFrame=2569 baseFrame=2569
+	extFrameInfo := &RtpExtFrameInfo{
+		Version:       uint8(2),
+		Start:         true,
+		Independent:   true,
+		Required:      true,
+		Base:          true,
+		CurrentFrame:  uint16(2569),
+		PreviousFrame: uint16(2569),
+		BaseFrame:     uint16(2569),
+	}
+
+	payload, err := extFrameInfo.Marshal()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if payload[1] != 184 {
+		t.Errorf("expected pktInfo 184, got %v", payload[1])
+	}
+
+	decoded := &RtpExtFrameInfo{}
+	err = decoded.Unmarshal(payload)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if *decoded != *extFrameInfo {
+		t.Errorf("expected %v, got %v", extFrameInfo, decoded)
+	}
+}
@@ -4,5 +4,6 @@ type RtpMetadata struct {
 	StreamId                    []byte
 	ScreenShareResolution       *RtpExtResolution
 	ScreenShareFrameInfo        *RtpExtFrameInfo
+	VideoFrameInfo              *RtpExtFrameInfo
 	AudioHeaderEncryptedPayload []byte
 }
@@ -26,8 +26,8 @@ func DecodeAudioMetadata(rtpPacket *rtp.Packet) (*RtpMetadata, error) {
 	}
 
 	audioHeader := rtpPacket.GetExtension(RTP_EXTENSION_ID_AUDIO_IV)
-	if len(audioHeader) != 9 {
-		return nil, fmt.Errorf("rtp extension audio iv expected length %v received %v", 9, len(audioHeader))
+	if len(audioHeader) != RTP_EXTENSION_AUDIO_IV_LENGTH {
+		return nil, fmt.Errorf("rtp extension audio iv expected length %v received %v", RTP_EXTENSION_AUDIO_IV_LENGTH, len(audioHeader))
 	}
 
 	return &RtpMetadata{
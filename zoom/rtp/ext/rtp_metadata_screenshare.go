@@ -28,7 +28,6 @@ func DecodeScreenShareMetadata(rtpPacket *rtp.Packet) (*RtpMetadata, error) {
 		resolutionMeta = &RtpExtResolution{}
 		err := resolutionMeta.Unmarshal(resolutionBytes)
 		if err != nil {
-			log.Fatal(err)
 			return nil, err
 		}
 	}
@@ -38,7 +37,6 @@ func DecodeScreenShareMetadata(rtpPacket *rtp.Packet) (*RtpMetadata, error) {
 		svcMeta = &RtpExtFrameInfo{}
 		err := svcMeta.Unmarshal(svcBytes)
 		if err != nil {
-			log.Fatal(err)
 			return nil, err
 		}
 	}
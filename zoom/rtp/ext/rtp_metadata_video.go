@@ -13,6 +13,7 @@ func DecodeVideoMetadata(rtpPacket *rtp.Packet) (*RtpMetadata, error) {
 	extensions := rtpPacket.GetExtensionIDs()
 	for _, id := range extensions {
 		switch id {
+		case RTP_EXTENSION_ID_VIDEO_FRAME_INFO:
 		default:
 			extensionData := rtpPacket.GetExtension(id)
 			log.Printf("rtp extensions found unknown ext id=%v data=%v", id, hex.EncodeToString(extensionData))
@@ -27,5 +28,17 @@ func DecodeVideoMetadata(rtpPacket *rtp.Packet) (*RtpMetadata, error) {
 		return nil, fmt.Errorf("payload type has unexpected value %v", rtpPacket.PayloadType)
 	}
 
-	return &RtpMetadata{}, nil
+	frameInfoBytes := rtpPacket.GetExtension(RTP_EXTENSION_ID_VIDEO_FRAME_INFO)
+	var frameInfo *RtpExtFrameInfo
+	if len(frameInfoBytes) > 0 {
+		frameInfo = &RtpExtFrameInfo{}
+		err := frameInfo.Unmarshal(frameInfoBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &RtpMetadata{
+		VideoFrameInfo: frameInfo,
+	}, nil
 }
@@ -0,0 +1,126 @@
+package rtp
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp/ext"
+)
+
+// all temporal layers, the frame info has 3 bits for the temporal id
+const MAX_TEMPORAL_ID = 7
+
+var (
+	ErrMissingReference = errors.New("frame refers to a frame that was never decoded")
+	ErrFrameLost        = errors.New("frames were lost")
+)
+
+/*
+LayerSelector decides which frames of a video or screenshare stream are passed on to the decoder, based on
+the ext.RtpExtFrameInfo that comes with every packet. There is one per SSRC.
+
+  - frames of a temporal layer above the maximum are dropped, lower layers never really depend on higher
+    ones so what remains decodes fine at a lower frame rate. PreviousFrame still names the frame right
+    before, so references to frames we dropped this way count as satisfied.
+  - nothing is passed on until an Independent frame arrives
+  - a frame whose PreviousFrame or BaseFrame was never passed on (lost, or held back while waiting) fails
+    with ErrMissingReference, a jump in CurrentFrame fails with ErrFrameLost. After either we wait for the
+    next Independent frame again.
+
+The last point only makes sense when the sender can be asked for a keyframe, without that (see SetHoldAfterLoss)
+frames are passed on regardless, a glitch beats a picture frozen until the sender happens to send the next one.
+*/
+type LayerSelector struct {
+	maxTemporalID uint8
+	holdAfterLoss bool
+	waiting       bool
+	// frames of all layers are counted, so a jump means whole frames got lost
+	lastFrame    uint16
+	hasLastFrame bool
+	// which frame counters were passed on since the last independent frame
+	passed frameSet
+	// which were dropped for being above maxTemporalID
+	dropped frameSet
+}
+
+// a bit for every value of the 16 bit frame counter
+type frameSet [1 << 16 / 64]uint64
+
+func NewLayerSelector(maxTemporalID uint8) *LayerSelector {
+	return &LayerSelector{
+		maxTemporalID: maxTemporalID,
+		holdAfterLoss: true,
+		waiting:       true,
+	}
+}
+
+func (selector *LayerSelector) SetMaxTemporalID(maxTemporalID uint8) {
+	selector.maxTemporalID = maxTemporalID
+}
+
+// SetHoldAfterLoss decides whether to wait for the next Independent frame after a loss, the default
+func (selector *LayerSelector) SetHoldAfterLoss(hold bool) {
+	selector.holdAfterLoss = hold
+}
+
+// Select returns whether the frame should be decoded
+func (selector *LayerSelector) Select(frameInfo *ext.RtpExtFrameInfo) (bool, error) {
+	// the counters wrap around, forget what was passed on half a wrap ago
+	selector.passed.set(frameInfo.CurrentFrame+1<<15, false)
+	selector.dropped.set(frameInfo.CurrentFrame+1<<15, false)
+	previous := selector.lastFrame
+	lost := selector.hasLastFrame && frameInfo.CurrentFrame-previous > 1
+	selector.lastFrame = frameInfo.CurrentFrame
+	selector.hasLastFrame = true
+
+	if frameInfo.TemporalID > selector.maxTemporalID {
+		selector.dropped.set(frameInfo.CurrentFrame, true)
+		if lost && !selector.waiting && selector.holdAfterLoss {
+			selector.waiting = true
+			return false, lostError(frameInfo, previous)
+		}
+		return false, nil
+	}
+	if frameInfo.Independent {
+		selector.passed = frameSet{}
+		selector.dropped = frameSet{}
+		selector.waiting = false
+	}
+	if selector.waiting {
+		return false, nil
+	}
+	if !selector.holdAfterLoss {
+		selector.passed.set(frameInfo.CurrentFrame, true)
+		return true, nil
+	}
+	if lost {
+		selector.waiting = true
+		return false, lostError(frameInfo, previous)
+	}
+	if !frameInfo.Independent {
+		for _, reference := range []uint16{frameInfo.PreviousFrame, frameInfo.BaseFrame} {
+			if !selector.passed.has(reference) && !selector.dropped.has(reference) {
+				selector.waiting = true
+				return false, fmt.Errorf("%w: frame %v needs %v", ErrMissingReference, frameInfo.CurrentFrame, reference)
+			}
+		}
+	}
+	selector.passed.set(frameInfo.CurrentFrame, true)
+	return true, nil
+}
+
+func lostError(frameInfo *ext.RtpExtFrameInfo, previous uint16) error {
+	return fmt.Errorf("%w: frame %v came after %v", ErrFrameLost, frameInfo.CurrentFrame, previous)
+}
+
+func (set *frameSet) has(frame uint16) bool {
+	return set[frame/64]&(1<<(frame%64)) != 0
+}
+
+func (set *frameSet) set(frame uint16, in bool) {
+	if in {
+		set[frame/64] |= 1 << (frame % 64)
+	} else {
+		set[frame/64] &^= 1 << (frame % 64)
+	}
+}
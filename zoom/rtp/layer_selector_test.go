@@ -0,0 +1,102 @@
+package rtp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp/ext"
+)
+
+func TestLayerSelector(t *testing.T) {
+	selector := NewLayerSelector(0)
+
+	frames := []struct {
+		frameInfo ext.RtpExtFrameInfo
+		selected  bool
+		err       error
+	}{
+		// we joined in the middle, wait for an independent frame
+		{ext.RtpExtFrameInfo{CurrentFrame: 9, PreviousFrame: 8, BaseFrame: 1}, false, nil},
+		{ext.RtpExtFrameInfo{Independent: true, Base: true, CurrentFrame: 10, PreviousFrame: 10, BaseFrame: 10}, true, nil},
+		// enhancement layer is dropped, the base layer doesn't need it
+		{ext.RtpExtFrameInfo{TemporalID: 1, CurrentFrame: 11, PreviousFrame: 10, BaseFrame: 10}, false, nil},
+		{ext.RtpExtFrameInfo{CurrentFrame: 12, PreviousFrame: 10, BaseFrame: 10}, true, nil},
+		// a reference to a frame we dropped on purpose is fine
+		{ext.RtpExtFrameInfo{TemporalID: 1, CurrentFrame: 13, PreviousFrame: 12, BaseFrame: 10}, false, nil},
+		{ext.RtpExtFrameInfo{CurrentFrame: 14, PreviousFrame: 13, BaseFrame: 10}, true, nil},
+		{ext.RtpExtFrameInfo{Independent: true, Base: true, CurrentFrame: 15, PreviousFrame: 15, BaseFrame: 15}, true, nil},
+		// 16 got lost
+		{ext.RtpExtFrameInfo{CurrentFrame: 17, PreviousFrame: 15, BaseFrame: 15}, false, ErrFrameLost},
+		{ext.RtpExtFrameInfo{CurrentFrame: 18, PreviousFrame: 17, BaseFrame: 15}, false, nil},
+		{ext.RtpExtFrameInfo{Independent: true, Base: true, CurrentFrame: 19, PreviousFrame: 19, BaseFrame: 19}, true, nil},
+		// frames before the independent frame are forgotten
+		{ext.RtpExtFrameInfo{CurrentFrame: 20, PreviousFrame: 17, BaseFrame: 19}, false, ErrMissingReference},
+	}
+	for i, frame := range frames {
+		selected, err := selector.Select(&frame.frameInfo)
+		if !errors.Is(err, frame.err) {
+			t.Errorf("frame %v: expected error %v, got %v", i, frame.err, err)
+		}
+		if selected != frame.selected {
+			t.Errorf("frame %v: expected selected=%v, got %v", i, frame.selected, selected)
+		}
+	}
+
+	selector.SetMaxTemporalID(MAX_TEMPORAL_ID)
+	selected, err := selector.Select(&ext.RtpExtFrameInfo{Independent: true, CurrentFrame: 21, PreviousFrame: 21, BaseFrame: 21})
+	if !selected || err != nil {
+		t.Errorf("expected the independent frame to be selected, got %v %v", selected, err)
+	}
+	selected, err = selector.Select(&ext.RtpExtFrameInfo{TemporalID: 2, CurrentFrame: 22, PreviousFrame: 21, BaseFrame: 21})
+	if !selected || err != nil {
+		t.Errorf("expected all layers to be selected, got %v %v", selected, err)
+	}
+}
+
+func TestLayerSelectorWithoutHold(t *testing.T) {
+	selector := NewLayerSelector(0)
+	selector.SetHoldAfterLoss(false)
+
+	frames := []struct {
+		frameInfo ext.RtpExtFrameInfo
+		selected  bool
+	}{
+		{ext.RtpExtFrameInfo{CurrentFrame: 9, PreviousFrame: 8, BaseFrame: 1}, false},
+		{ext.RtpExtFrameInfo{Independent: true, Base: true, CurrentFrame: 10, PreviousFrame: 10, BaseFrame: 10}, true},
+		{ext.RtpExtFrameInfo{TemporalID: 1, CurrentFrame: 11, PreviousFrame: 10, BaseFrame: 10}, false},
+		// 12 got lost, nobody can be asked for a keyframe so we carry on
+		{ext.RtpExtFrameInfo{CurrentFrame: 13, PreviousFrame: 12, BaseFrame: 10}, true},
+		{ext.RtpExtFrameInfo{CurrentFrame: 14, PreviousFrame: 13, BaseFrame: 10}, true},
+	}
+	for i, frame := range frames {
+		selected, err := selector.Select(&frame.frameInfo)
+		if err != nil {
+			t.Errorf("frame %v: expected no error, got %v", i, err)
+		}
+		if selected != frame.selected {
+			t.Errorf("frame %v: expected selected=%v, got %v", i, frame.selected, selected)
+		}
+	}
+}
+
+func TestFrameInfosEvictOldest(t *testing.T) {
+	parser := NewZoomRtpDecoder(STREAM_TYPE_VIDEO)
+	// starts right before the rtp timestamp wraps around
+	start := uint32(0xFFFFFFFF - 3000*10)
+	for i := 0; i <= maxFrameInfos; i++ {
+		parser.rememberFrameInfo(1, start+uint32(i*3000), &ext.RtpExtFrameInfo{CurrentFrame: uint16(i)})
+	}
+
+	if len(parser.frameInfos[1]) != maxFrameInfos {
+		t.Errorf("expected %v frame infos, got %v", maxFrameInfos, len(parser.frameInfos[1]))
+	}
+	if frameInfo := parser.takeFrameInfo(1, start); frameInfo != nil {
+		t.Errorf("expected the oldest frame info to be evicted, got %+v", frameInfo)
+	}
+	for _, i := range []int{1, maxFrameInfos} {
+		frameInfo := parser.takeFrameInfo(1, start+uint32(i*3000))
+		if frameInfo == nil || frameInfo.CurrentFrame != uint16(i) {
+			t.Errorf("expected the frame info of frame %v to be kept, got %+v", i, frameInfo)
+		}
+	}
+}
@@ -53,34 +53,38 @@ func (roster *ZoomParticipantRoster) AddSsrcForParticipant(userId int, ssrc int)
 	return nil
 }
 
-func (roster *ZoomParticipantRoster) GetSecretNonceForSSRC(ssrcNeedle int) ([]byte, error) {
-	var secretNonce []byte
+func (roster *ZoomParticipantRoster) getParticipantForSSRC(ssrcNeedle int) *Participant {
 	for _, participant := range roster.participants {
-		found := false
 		for _, ssrcHay := range participant.ssrcs {
 			if ssrcNeedle == ssrcHay {
-				found = true
-				secretNonce = participant.secretNonce
-				break
+				return participant
 			}
 		}
 		// There seems to be a relation between userId (17778240) and ssrc id (17778242)
 		// so let's try converting ssrc to node id as a backup as well.
 		if (participant.userId/10)*10 == (ssrcNeedle/10)*10 {
-			found = true
-			secretNonce = participant.secretNonce
-		}
-
-		if found {
-			break
+			return participant
 		}
 	}
+	return nil
+}
 
-	if len(secretNonce) == 0 {
+func (roster *ZoomParticipantRoster) GetSecretNonceForSSRC(ssrcNeedle int) ([]byte, error) {
+	participant := roster.getParticipantForSSRC(ssrcNeedle)
+	if participant == nil || len(participant.secretNonce) == 0 {
 		return nil, ErrSsrcMissing
 	}
 
-	return secretNonce, nil
+	return participant.secretNonce, nil
+}
+
+func (roster *ZoomParticipantRoster) GetUserIdForSSRC(ssrcNeedle int) (int, error) {
+	participant := roster.getParticipantForSSRC(ssrcNeedle)
+	if participant == nil {
+		return 0, ErrSsrcMissing
+	}
+
+	return participant.userId, nil
 }
 
 func (roster *ZoomParticipantRoster) SetSharedMeetingKey(sharedMeetingKey []byte) {
@@ -2,7 +2,6 @@ package rtp
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/pion/rtcp"
 )
@@ -10,7 +9,6 @@ import (
 func RtcpProcess(rawPkt []byte) ([]rtcp.Packet, error) {
 	rtcpPackets, err := rtcp.Unmarshal(rawPkt)
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
 
@@ -2,8 +2,11 @@ package rtp
 
 import (
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"sync/atomic"
+	"time"
 
 	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/h264"
 	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/opus"
@@ -14,22 +17,65 @@ import (
 	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
 )
 
+const (
+	// how many packets the sample builder waits for one that is missing
+	MAX_LATE_PACKETS = 400
+	// video gives up on missing packets sooner, after that we ask for a keyframe anyway
+	VIDEO_MAX_LATE    = 500 * time.Millisecond
+	VIDEO_SAMPLE_RATE = 90000
+
+	// frame infos kept per ssrc for frames the sample builder may still return
+	maxFrameInfos = 128
+)
+
+// Sample is a depacketized sample along with who sent it
+type Sample struct {
+	*media.Sample
+	SSRC   uint32
+	UserID int
+}
+
 type ZoomRtpDecoder struct {
 	streamType        StreamType
 	sampleBuilders    map[ /*ssrc*/ uint32]*samplebuilder.SampleBuilder
 	decryptors        map[ /*ssrc*/ uint32]*crypto.AesGcmCrypto
 	ParticipantRoster *ZoomParticipantRoster
+	// screenshares need some work before anything can play them
+	normalizers map[ /*ssrc*/ uint32]*h264.ScreenShareNormalizer
+	// the frame info of the frames still in the sample builders, by rtp timestamp
+	frameInfos     map[ /*ssrc*/ uint32]map[ /*timestamp*/ uint32]*ext.RtpExtFrameInfo
+	layerSelectors map[ /*ssrc*/ uint32]*LayerSelector
+	// atomic, see SetMaxTemporalID
+	maxTemporalID uint32
+	// video and screenshare that lost packets are held back until their next keyframe
+	waitingForKeyFrame map[ /*ssrc*/ uint32]bool
+
+	// OnKeyFrameNeeded is called for every frame held back after packets of the ssrc were lost. Without it
+	// nothing is held back, there would be no telling when the next keyframe comes.
+	OnKeyFrameNeeded func(ssrc uint32)
 }
 
 func NewZoomRtpDecoder(streamType StreamType) *ZoomRtpDecoder {
 	return &ZoomRtpDecoder{
 		sampleBuilders:    make(map[uint32]*samplebuilder.SampleBuilder),
 		decryptors:        make(map[uint32]*crypto.AesGcmCrypto),
+		normalizers:       make(map[uint32]*h264.ScreenShareNormalizer),
+		frameInfos:        make(map[uint32]map[uint32]*ext.RtpExtFrameInfo),
+		layerSelectors:    make(map[uint32]*LayerSelector),
+		maxTemporalID:     MAX_TEMPORAL_ID,
 		ParticipantRoster: NewParticipantRoster(),
 		streamType:        streamType,
 	}
 }
 
+/*
+SetMaxTemporalID drops the video and screenshare frames of temporal layers above it, 0 only keeps the base
+layer which is good enough for thumbnails. MAX_TEMPORAL_ID (the default) keeps everything.
+*/
+func (parser *ZoomRtpDecoder) SetMaxTemporalID(maxTemporalID uint8) {
+	atomic.StoreUint32(&parser.maxTemporalID, uint32(maxTemporalID))
+}
+
 func (parser *ZoomRtpDecoder) getDecryptorFor(ssrc uint32) (*crypto.AesGcmCrypto, error) {
 	if parser.decryptors[ssrc] == nil {
 		// 1. Fetch the secretNonce for the ssrc
@@ -73,30 +119,26 @@ func (parser *ZoomRtpDecoder) getSampleBuilderFor(ssrc uint32) (*samplebuilder.S
 			return nil, err
 		}
 
-		// TODO: think about reasonable max late
-		maxLate := uint16(400)
-		// TODO: add audio support
-		var depacketizer rtp.Depacketizer
 		switch parser.streamType {
 		case STREAM_TYPE_SCREENSHARE, STREAM_TYPE_VIDEO:
-			depacketizer = h264.NewVideoDepacketizer(decryptor)
+			if parser.streamType == STREAM_TYPE_SCREENSHARE {
+				parser.normalizers[ssrc] = h264.NewScreenShareNormalizer()
+			}
+			parser.layerSelectors[ssrc] = NewLayerSelector(MAX_TEMPORAL_ID)
+			parser.sampleBuilders[ssrc] = samplebuilder.New(MAX_LATE_PACKETS, h264.NewVideoDepacketizer(decryptor), VIDEO_SAMPLE_RATE, samplebuilder.WithMaxTimeDelay(VIDEO_MAX_LATE))
 		case STREAM_TYPE_AUDIO:
-			depacketizer = opus.NewAudioDepacketizer(decryptor)
+			// TODO: fix sample rate
+			parser.sampleBuilders[ssrc] = samplebuilder.New(MAX_LATE_PACKETS, opus.NewAudioDepacketizer(decryptor), 1)
 		}
-
-		// TODO: fix sample rate
-		sampleRate := uint32(1)
-		parser.sampleBuilders[ssrc] = samplebuilder.New(maxLate, depacketizer, sampleRate)
 	}
 	return parser.sampleBuilders[ssrc], nil
 }
 
-func (parser *ZoomRtpDecoder) Decode(rawPkt []byte) (*media.Sample, error) {
+func (parser *ZoomRtpDecoder) Decode(rawPkt []byte) (*Sample, error) {
 	// 1. Decode the RTP packet
 	rtpPacket := &rtp.Packet{}
 	err := rtpPacket.Unmarshal(rawPkt)
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
 
@@ -140,6 +182,14 @@ func (parser *ZoomRtpDecoder) Decode(rawPkt []byte) (*media.Sample, error) {
 		rtpPacket = clonedRtpPacket
 	}
 
+	frameInfo := metadata.ScreenShareFrameInfo
+	if metadata.VideoFrameInfo != nil {
+		frameInfo = metadata.VideoFrameInfo
+	}
+	if frameInfo != nil {
+		parser.rememberFrameInfo(rtpPacket.SSRC, rtpPacket.Timestamp, frameInfo)
+	}
+
 	// 4. Push the RTP packet to the sampleBuilder, this re-orders the packets based
 	// on the RTP Sequence, they may arrive out of order aggregates them and calls
 	// the correct depacketizer (VideoDepacketizer / AudioDepacketizer).
@@ -150,6 +200,101 @@ func (parser *ZoomRtpDecoder) Decode(rawPkt []byte) (*media.Sample, error) {
 	// WARNING: SAMPLE THAT WAS POPPED MAY NOT BE ASSOCIATED WITH THE RTP PACKET!
 	// THIS IS BECAUSE THE SAMPLE BUILDER ALWAYS LAGS BEHIND ONE PACKET AND MAY
 	// AGGREGATE PACKETS IN THE CASE OF VIDEO STREAMS.
+	// It does belong to the same ssrc though, there is a sample builder per ssrc.
+	if sample == nil {
+		return nil, nil
+	}
+	if selector := parser.layerSelectors[rtpPacket.SSRC]; selector != nil {
+		if sample.PrevDroppedPackets > 0 {
+			parser.holdUntilKeyFrame(rtpPacket.SSRC)
+		}
+		frameInfo := parser.takeFrameInfo(rtpPacket.SSRC, sample.PacketTimestamp)
+		if frameInfo != nil {
+			selector.SetMaxTemporalID(uint8(atomic.LoadUint32(&parser.maxTemporalID)))
+			selector.SetHoldAfterLoss(parser.OnKeyFrameNeeded != nil)
+			selected, err := selector.Select(frameInfo)
+			if errors.Is(err, ErrMissingReference) || errors.Is(err, ErrFrameLost) {
+				parser.holdUntilKeyFrame(rtpPacket.SSRC)
+				parser.keyFrameNeeded(rtpPacket.SSRC)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !selected {
+				return nil, nil
+			}
+		}
+	}
+	if normalizer := parser.normalizers[rtpPacket.SSRC]; normalizer != nil {
+		sample.Data, err = normalizer.Normalize(sample.Data)
+		if err != nil {
+			return nil, err
+		}
+		if sample.Data == nil {
+			return nil, nil
+		}
+	}
+	if parser.waitingForKeyFrame[rtpPacket.SSRC] {
+		if !h264.IsKeyFrame(sample.Data) {
+			// keep asking, the request may have been lost as well
+			parser.keyFrameNeeded(rtpPacket.SSRC)
+			return nil, nil
+		}
+		delete(parser.waitingForKeyFrame, rtpPacket.SSRC)
+	}
+
+	userId, err := parser.ParticipantRoster.GetUserIdForSSRC(int(rtpPacket.SSRC))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sample{
+		Sample: sample,
+		SSRC:   rtpPacket.SSRC,
+		UserID: userId,
+	}, nil
+}
+
+func (parser *ZoomRtpDecoder) holdUntilKeyFrame(ssrc uint32) {
+	if parser.OnKeyFrameNeeded == nil {
+		// screen content can go minutes without a keyframe
+		return
+	}
+	if parser.waitingForKeyFrame == nil {
+		parser.waitingForKeyFrame = make(map[uint32]bool)
+	}
+	parser.waitingForKeyFrame[ssrc] = true
+}
+
+func (parser *ZoomRtpDecoder) keyFrameNeeded(ssrc uint32) {
+	if parser.OnKeyFrameNeeded != nil {
+		parser.OnKeyFrameNeeded(ssrc)
+	}
+}
+
+func (parser *ZoomRtpDecoder) rememberFrameInfo(ssrc uint32, timestamp uint32, frameInfo *ext.RtpExtFrameInfo) {
+	frameInfos := parser.frameInfos[ssrc]
+	if frameInfos == nil {
+		frameInfos = make(map[uint32]*ext.RtpExtFrameInfo)
+		parser.frameInfos[ssrc] = frameInfos
+	}
+	frameInfos[timestamp] = frameInfo
+
+	// frames the sample builder gave up on are never taken, forget the oldest rather than the frames in flight
+	if len(frameInfos) > maxFrameInfos {
+		oldest, oldestAge := timestamp, int32(0)
+		for remembered := range frameInfos {
+			// the signed difference takes care of wrap arounds
+			if age := int32(timestamp - remembered); age > oldestAge {
+				oldest, oldestAge = remembered, age
+			}
+		}
+		delete(frameInfos, oldest)
+	}
+}
 
-	return sample, nil
+func (parser *ZoomRtpDecoder) takeFrameInfo(ssrc uint32, timestamp uint32) *ext.RtpExtFrameInfo {
+	frameInfo := parser.frameInfos[ssrc][timestamp]
+	delete(parser.frameInfos[ssrc], timestamp)
+	return frameInfo
 }
@@ -1,154 +1,226 @@
 package rtp
 
 import (
+	"crypto/rand"
 	"encoding/binary"
-	"encoding/hex"
-	"log"
 
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/h264"
 	"github.com/RealKeyboardWarrior/zoomer/zoom/crypto"
 	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp/ext"
 	"github.com/pion/rtp"
 )
 
-type ZoomRtpEncoder struct {
-	id             int
-	ssrc           int
-	resolution     *ext.RtpExtResolution
-	messageCounter int
-	timestamp      int
-
-	// Frame information
-	baseFrameCounter    int
-	currentFrameCounter int
-
-	// Encryption information
-	ParticipantRoster *ZoomParticipantRoster
-}
+const (
+	AUDIO_PAYLOAD_TYPE       = 99
+	VIDEO_PAYLOAD_TYPE       = 98
+	SCREENSHARE_PAYLOAD_TYPE = 99
 
-func NewZoomRtpEncoder(roster *ZoomParticipantRoster, ssrc int, id int, width, height int) *ZoomRtpEncoder {
-	return &ZoomRtpEncoder{
-		id:   id,
-		ssrc: ssrc,
-		resolution: &ext.RtpExtResolution{
-			Width:  uint16(width),
-			Height: uint16(height),
-		},
+	FRAME_INFO_VERSION = 2
+)
 
-		messageCounter: 0,
-		timestamp:      2746202358,
+/*
+ZoomRtpEncoder turns our own media into encrypted rtp packets, the reverse of what ZoomRtpDecoder does.
 
-		ParticipantRoster: roster,
-	}
-}
+Every frame is encrypted as a whole with a fresh IV and then split into packets by the NaluPacketizer, all
+packets of a frame share the rtp timestamp and the last one has the marker bit set.
 
-func (parser *ZoomRtpEncoder) Encode(payload []byte) ([]byte, error) {
+Audio packets don't carry the IV in the payload like video does, the length of the ciphertext and the first half
+of the IV go into the RTP_EXTENSION_ID_AUDIO_IV extension and the rest of the IV starts the payload.
+*/
+type ZoomRtpEncoder struct {
+	streamType StreamType
+	ssrc       uint32
+	encryptor  *crypto.AesGcmCrypto
+	packetizer *h264.NaluPacketizer
+	// only sent along with screenshares
+	resolution *ext.RtpExtResolution
+
+	sequenceNumber uint16
+	timestamp      uint32
+	// the IV is a random prefix followed by a frame counter so it never repeats for our key
+	ivPrefix [4]byte
+	counter  uint64
+
+	// see ext.RtpExtFrameInfo
+	currentFrame uint16
+	baseFrame    uint16
+}
 
-	encryptedPayload, err := encryptPayloadWithRoster(parser.ParticipantRoster, parser.ssrc, parser.messageCounter, payload)
+func NewZoomRtpEncoder(streamType StreamType, sharedMeetingKey []byte, secretNonce []byte, ssrc uint32) (*ZoomRtpEncoder, error) {
+	var keyType crypto.AesKeyType
+	switch streamType {
+	case STREAM_TYPE_SCREENSHARE:
+		keyType = crypto.KEY_TYPE_SCREENSHARE
+	case STREAM_TYPE_VIDEO:
+		keyType = crypto.KEY_TYPE_VIDEO
+	case STREAM_TYPE_AUDIO:
+		keyType = crypto.KEY_TYPE_AUDIO
+	}
+	encryptor, err := crypto.NewAesGcmCrypto(sharedMeetingKey, secretNonce, keyType)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: properly NALU packetize the payload if too long
-	packetizedPayload := append(make([]byte, 1), encryptedPayload...)
-
-	// Wrap encoded payload in RTP packets
-	// TODO: multiple packets, currently only single packets
-	parser.timestamp = parser.timestamp + (parser.messageCounter * 15000)
-	p := &rtp.Packet{
-		Header: rtp.Header{
-			Version:          2,
-			Padding:          false,
-			Extension:        false,
-			Marker:           false,
-			PayloadType:      99,
-			SequenceNumber:   uint16(parser.messageCounter),
-			Timestamp:        uint32(parser.timestamp),
-			SSRC:             uint32(parser.ssrc),
-			CSRC:             []uint32{},
-			ExtensionProfile: 0,
-			Extensions:       []rtp.Extension{},
-		},
-		Payload:     packetizedPayload,
-		PaddingSize: 0,
+	encoder := &ZoomRtpEncoder{
+		streamType: streamType,
+		ssrc:       ssrc,
+		encryptor:  encryptor,
+		packetizer: h264.NewNaluPacketizer(),
 	}
-
-	// TODO: increment UUID whenever reconnecting, prob big endian but single byte?
-	p.Header.SetExtension(ext.RTP_EXTENSION_ID_UUID, []byte{0x01})
-	if parser.resolution != nil {
-		resolution, err := parser.resolution.Marshal()
-		if err != nil {
-			return nil, err
-		}
-		p.Header.SetExtension(ext.RTP_EXTENSION_ID_SCREENSHARE_RESOLUTION, resolution)
-	}
-
-	// Set extension screen size
-	rtpResolution, err := parser.resolution.Marshal()
+	// random starting points like any other rtp sender
+	var start [6]byte
+	_, err = rand.Read(start[:])
 	if err != nil {
 		return nil, err
 	}
-	p.Header.SetExtension(ext.RTP_EXTENSION_ID_SCREENSHARE_RESOLUTION, rtpResolution)
-
-	// TODO: extension frame info should probably be more advanced
-	rtpFrameInfo := &ext.RtpExtFrameInfo{
-		Version:       2,
-		Start:         true,  // TODO: NALU packetizer
-		End:           true,  // TODO: NALU packetize
-		Independent:   false, // TODO: passed along with payload
-		Required:      false, // TODO: passed along with payload
-		Base:          false, // TODO: passed along with payload
-		TemporalID:    0,     // TODO: ????
-		CurrentFrame:  uint16(parser.currentFrameCounter + 1),
-		PreviousFrame: uint16(parser.currentFrameCounter),
-		BaseFrame:     uint16(parser.baseFrameCounter),
-	}
-	rtpFrameInfoBytes, err := rtpFrameInfo.Marshal()
+	encoder.sequenceNumber = binary.BigEndian.Uint16(start[0:2])
+	encoder.timestamp = binary.BigEndian.Uint32(start[2:6])
+	_, err = rand.Read(encoder.ivPrefix[:])
 	if err != nil {
 		return nil, err
 	}
-	p.Header.SetExtension(ext.RTP_EXTENSION_ID_SCREENSHARE_FRAME_INFO, rtpFrameInfoBytes)
+	return encoder, nil
+}
 
-	rawPkt, err := p.Marshal()
-	if err != nil {
-		log.Fatal(err)
-		return nil, err
+// SetResolution sets the size of the screen being shared, it is sent along with every screenshare packet
+func (encoder *ZoomRtpEncoder) SetResolution(width, height int) {
+	encoder.resolution = &ext.RtpExtResolution{
+		Width:  uint16(width),
+		Height: uint16(height),
 	}
-
-	return rawPkt, nil
 }
 
-func encryptPayloadWithRoster(roster *ZoomParticipantRoster, ssrc int, messageCounter int, plaintext []byte) ([]byte, error) {
-	secretNonce, err := roster.GetSecretNonceForSSRC(ssrc)
+/*
+Encode encrypts a single frame and returns its rtp packets. For audio that is one opus packet, for video and
+screenshare one annex B access unit. The duration is in rtp clock ticks, the number of samples per channel for
+audio and 90kHz ticks for video, the timestamp of the next frame is advanced by it.
+*/
+func (encoder *ZoomRtpEncoder) Encode(frame []byte, keyFrame bool, duration uint32) ([][]byte, error) {
+	IV := make([]byte, crypto.LEN_IV)
+	copy(IV, encoder.ivPrefix[:])
+	binary.BigEndian.PutUint64(IV[4:], encoder.counter)
+
+	ciphertextWithTag, err := encoder.encryptor.Encrypt(IV, frame)
 	if err != nil {
-		return nil, ErrParticipantExists
+		return nil, err
 	}
+	encodedPayload := crypto.NewRtpEncryptedPayload(0, IV, ciphertextWithTag).Marshal()
 
-	sharedMeetingKey, err := roster.GetSharedMeetingKey()
-	if err != nil {
-		return nil, ErrParticipantExists
+	var rawPkts [][]byte
+	if encoder.streamType == STREAM_TYPE_AUDIO {
+		rawPkt, err := encoder.encodeAudio(encodedPayload)
+		if err != nil {
+			return nil, err
+		}
+		rawPkts = [][]byte{rawPkt}
+	} else {
+		rawPkts, err = encoder.encodeVideo(encodedPayload, keyFrame)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	encoder.counter++
+	encoder.timestamp += duration
+	return rawPkts, nil
+}
+
+func (encoder *ZoomRtpEncoder) nextPacket(payloadType uint8, payload []byte) *rtp.Packet {
+	p := &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    payloadType,
+			SequenceNumber: encoder.sequenceNumber,
+			Timestamp:      encoder.timestamp,
+			SSRC:           encoder.ssrc,
+		},
+		Payload: payload,
 	}
+	encoder.sequenceNumber++
+	return p
+}
 
-	// Build 12 byte IV
-	IV := make([]byte, 12)
-	binary.BigEndian.PutUint16(IV, uint16(messageCounter))
-	IV = IV[:12]
+func (encoder *ZoomRtpEncoder) encodeAudio(encodedPayload []byte) ([]byte, error) {
+	header, payload := crypto.SplitAudioHack(encodedPayload, ext.RTP_EXTENSION_AUDIO_IV_LENGTH)
 
-	// TODO: add keyType, hardcoded to screenshare!
-	encryptor, err := crypto.NewAesGcmCrypto(sharedMeetingKey, secretNonce, 0x02)
+	p := encoder.nextPacket(AUDIO_PAYLOAD_TYPE, payload)
+	err := p.Header.SetExtension(ext.RTP_EXTENSION_ID_AUDIO_IV, header)
 	if err != nil {
 		return nil, err
 	}
+	return p.Marshal()
+}
 
-	ciphertextWithTag, err := encryptor.Encrypt(IV, plaintext)
+func (encoder *ZoomRtpEncoder) encodeVideo(encodedPayload []byte, keyFrame bool) ([][]byte, error) {
+	fragments, err := encoder.packetizer.Marshal(encodedPayload)
 	if err != nil {
 		return nil, err
 	}
 
-	if true {
-		log.Printf("encrypted body key=%v sn=%v iv=%v ciphertextWithTag=%v", hex.EncodeToString(sharedMeetingKey), hex.EncodeToString(secretNonce), hex.EncodeToString(IV), hex.EncodeToString(ciphertextWithTag))
+	// keyframes start over, nothing before them is needed to decode what follows
+	previousFrame := encoder.currentFrame
+	encoder.currentFrame++
+	if keyFrame {
+		previousFrame = encoder.currentFrame
+		encoder.baseFrame = encoder.currentFrame
+	}
+
+	payloadType := uint8(VIDEO_PAYLOAD_TYPE)
+	frameInfoID := uint8(ext.RTP_EXTENSION_ID_VIDEO_FRAME_INFO)
+	if encoder.streamType == STREAM_TYPE_SCREENSHARE {
+		payloadType = SCREENSHARE_PAYLOAD_TYPE
+		frameInfoID = ext.RTP_EXTENSION_ID_SCREENSHARE_FRAME_INFO
 	}
 
-	encodedPayload := crypto.NewRtpEncryptedPayload(0, IV, ciphertextWithTag)
-	encodedPayloadInBytes := encodedPayload.Marshal()
-	return encodedPayloadInBytes, nil
+	rawPkts := make([][]byte, 0, len(fragments))
+	for i, fragment := range fragments {
+		last := i == len(fragments)-1
+		p := encoder.nextPacket(payloadType, fragment)
+		p.Header.Marker = last
+
+		frameInfo := &ext.RtpExtFrameInfo{
+			Version:       FRAME_INFO_VERSION,
+			Start:         i == 0,
+			End:           last,
+			Independent:   keyFrame,
+			Required:      keyFrame,
+			Base:          keyFrame,
+			CurrentFrame:  encoder.currentFrame,
+			PreviousFrame: previousFrame,
+			BaseFrame:     encoder.baseFrame,
+		}
+		frameInfoBytes, err := frameInfo.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		err = p.Header.SetExtension(frameInfoID, frameInfoBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if encoder.streamType == STREAM_TYPE_SCREENSHARE {
+			// TODO: increment UUID whenever reconnecting, prob big endian but single byte?
+			err = p.Header.SetExtension(ext.RTP_EXTENSION_ID_UUID, []byte{0x01})
+			if err != nil {
+				return nil, err
+			}
+			if encoder.resolution != nil {
+				resolution, err := encoder.resolution.Marshal()
+				if err != nil {
+					return nil, err
+				}
+				err = p.Header.SetExtension(ext.RTP_EXTENSION_ID_SCREENSHARE_RESOLUTION, resolution)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		rawPkt, err := p.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		rawPkts = append(rawPkts, rawPkt)
+	}
+	return rawPkts, nil
 }
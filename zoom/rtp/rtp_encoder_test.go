@@ -0,0 +1,201 @@
+package rtp
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp/ext"
+	"github.com/pion/rtp"
+)
+
+var (
+	testSharedMeetingKey = bytes.Repeat([]byte{0x11}, 32)
+	testSecretNonce      = bytes.Repeat([]byte{0x22}, 16)
+	testUserID           = 16778240
+	testSsrc             = uint32(16778242)
+)
+
+// roundTrip encodes the frames and decodes them the way everyone else in the meeting does
+func roundTrip(t *testing.T, streamType StreamType, frames [][]byte, keyFrames []bool) ([][]byte, [][]byte) {
+	encoder, err := NewZoomRtpEncoder(streamType, testSharedMeetingKey, testSecretNonce, testSsrc)
+	if err != nil {
+		t.Error(err)
+		return nil, nil
+	}
+	encoder.SetResolution(1280, 720)
+
+	decoder := NewZoomRtpDecoder(streamType)
+	decoder.ParticipantRoster.SetSharedMeetingKey(testSharedMeetingKey)
+	decoder.ParticipantRoster.AddParticipant(testUserID, testSecretNonce)
+	decoder.ParticipantRoster.AddSsrcForParticipant(testUserID, int(testSsrc))
+
+	var rawPkts, received [][]byte
+	for i, frame := range frames {
+		pkts, err := encoder.Encode(frame, keyFrames[i], 3000)
+		if err != nil {
+			t.Error(err)
+			return nil, nil
+		}
+		for _, rawPkt := range pkts {
+			rawPkts = append(rawPkts, rawPkt)
+			sample, err := decoder.Decode(rawPkt)
+			if err != nil {
+				t.Error(err)
+				return nil, nil
+			}
+			if sample != nil {
+				if sample.UserID != testUserID {
+					t.Errorf("expected the packet of %v, got %v", testUserID, sample.UserID)
+				}
+				received = append(received, sample.Data)
+			}
+		}
+	}
+	return rawPkts, received
+}
+
+func TestEncoderAudioRoundTrip(t *testing.T) {
+	packets := [][]byte{{0xF8, 0xFF, 0xFE}, {0xF8, 0x01, 0x02, 0x03}, {0xF8, 0x04}}
+	rawPkts, received := roundTrip(t, STREAM_TYPE_AUDIO, packets, []bool{true, true, true})
+
+	if len(rawPkts) != 3 {
+		t.Errorf("expected a packet per opus packet, got %v", len(rawPkts))
+	}
+	// the sample builder holds on to the last packet
+	if len(received) != 2 || !bytes.Equal(received[0], packets[0]) || !bytes.Equal(received[1], packets[1]) {
+		t.Errorf("expected %v, got %v", packets[:2], received)
+	}
+}
+
+func TestEncoderFragmentsVideo(t *testing.T) {
+	for _, streamType := range []StreamType{STREAM_TYPE_VIDEO, STREAM_TYPE_SCREENSHARE} {
+		// screenshares are normalized to annex B on the way in, so send that
+		keyFrame := append([]byte{0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1e, 0, 0, 0, 1, 0x68, 0xce, 0x3c, 0x80, 0, 0, 0, 1}, bytes.Repeat([]byte{0x65}, 2000)...)
+		frames := [][]byte{keyFrame, {0, 0, 0, 1, 0x41, 0x01}, {0, 0, 0, 1, 0x41, 0x02}}
+		rawPkts, received := roundTrip(t, streamType, frames, []bool{true, false, false})
+
+		if len(received) != 2 || !bytes.Equal(received[0], frames[0]) || !bytes.Equal(received[1], frames[1]) {
+			t.Errorf("stream %v: expected the first two frames back, got %v", streamType, len(received))
+			continue
+		}
+
+		frameInfoID := uint8(ext.RTP_EXTENSION_ID_VIDEO_FRAME_INFO)
+		if streamType == STREAM_TYPE_SCREENSHARE {
+			frameInfoID = ext.RTP_EXTENSION_ID_SCREENSHARE_FRAME_INFO
+		}
+		// 3 fragments for the keyframe, then single packets
+		expected := []ext.RtpExtFrameInfo{
+			{Version: 2, Start: true, Independent: true, Required: true, Base: true, CurrentFrame: 1, PreviousFrame: 1, BaseFrame: 1},
+			{Version: 2, Independent: true, Required: true, Base: true, CurrentFrame: 1, PreviousFrame: 1, BaseFrame: 1},
+			{Version: 2, End: true, Independent: true, Required: true, Base: true, CurrentFrame: 1, PreviousFrame: 1, BaseFrame: 1},
+			{Version: 2, Start: true, End: true, CurrentFrame: 2, PreviousFrame: 1, BaseFrame: 1},
+			{Version: 2, Start: true, End: true, CurrentFrame: 3, PreviousFrame: 2, BaseFrame: 1},
+		}
+		if len(rawPkts) != len(expected) {
+			t.Errorf("stream %v: expected %v packets, got %v", streamType, len(expected), len(rawPkts))
+			continue
+		}
+		var previous *rtp.Packet
+		for i, rawPkt := range rawPkts {
+			p := &rtp.Packet{}
+			err := p.Unmarshal(rawPkt)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			frameInfo := ext.RtpExtFrameInfo{}
+			err = frameInfo.Unmarshal(p.GetExtension(frameInfoID))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if frameInfo != expected[i] {
+				t.Errorf("stream %v packet %v: expected %v, got %v", streamType, i, &expected[i], &frameInfo)
+			}
+			if p.Marker != expected[i].End {
+				t.Errorf("stream %v packet %v: marker should be set on the last packet of a frame only", streamType, i)
+			}
+			if previous != nil {
+				if p.SequenceNumber != previous.SequenceNumber+1 {
+					t.Errorf("stream %v packet %v: sequence numbers are not consecutive", streamType, i)
+				}
+				if expected[i].Start && p.Timestamp != previous.Timestamp+3000 {
+					t.Errorf("stream %v packet %v: expected the timestamp to advance by 3000", streamType, i)
+				}
+				if !expected[i].Start && p.Timestamp != previous.Timestamp {
+					t.Errorf("stream %v packet %v: fragments should share the timestamp", streamType, i)
+				}
+			}
+			previous = p
+		}
+	}
+}
+
+func TestDecoderRecoversFromLoss(t *testing.T) {
+	encoder, err := NewZoomRtpEncoder(STREAM_TYPE_VIDEO, testSharedMeetingKey, testSecretNonce, testSsrc)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	decoder := NewZoomRtpDecoder(STREAM_TYPE_VIDEO)
+	decoder.ParticipantRoster.SetSharedMeetingKey(testSharedMeetingKey)
+	decoder.ParticipantRoster.AddParticipant(testUserID, testSecretNonce)
+	decoder.ParticipantRoster.AddSsrcForParticipant(testUserID, int(testSsrc))
+	var requested []uint32
+	decoder.OnKeyFrameNeeded = func(ssrc uint32) {
+		requested = append(requested, ssrc)
+	}
+
+	keyFrame := []byte{0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1e, 0, 0, 0, 1, 0x68, 0xce, 0x3c, 0x80, 0, 0, 0, 1, 0x65, 0x88, 0x84}
+	var frames [][]byte
+	for i := 0; i < 10; i++ {
+		if i == 0 || i == 5 {
+			frames = append(frames, keyFrame)
+		} else {
+			frames = append(frames, []byte{0, 0, 0, 1, 0x41, byte(i)})
+		}
+	}
+
+	var received [][]byte
+	var lostErr error
+	for i, frame := range frames {
+		// long frames so the sample builder gives up on the missing one quickly
+		rawPkts, err := encoder.Encode(frame, i == 0 || i == 5, VIDEO_SAMPLE_RATE/3)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if i == 2 {
+			continue
+		}
+		for _, rawPkt := range rawPkts {
+			sample, err := decoder.Decode(rawPkt)
+			if err != nil {
+				lostErr = err
+				continue
+			}
+			if sample != nil {
+				received = append(received, sample.Data)
+			}
+		}
+	}
+
+	if !errors.Is(lostErr, ErrFrameLost) {
+		t.Errorf("expected ErrFrameLost, got %v", lostErr)
+	}
+	if len(requested) == 0 || requested[0] != testSsrc {
+		t.Errorf("expected a keyframe request for %v, got %v", testSsrc, requested)
+	}
+	// the frames after the loss are held back until the next keyframe, the sample builder still holds on to the last ones
+	expected := [][]byte{frames[0], frames[1], frames[5], frames[6], frames[7]}
+	if len(received) != len(expected) {
+		t.Errorf("expected %v frames, got %x", len(expected), received)
+		return
+	}
+	for i := range expected {
+		if !bytes.Equal(received[i], expected[i]) {
+			t.Errorf("frame %v: expected %x, got %x", i, expected[i], received[i])
+		}
+	}
+}
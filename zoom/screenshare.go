@@ -0,0 +1,120 @@
+package zoom
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/h264"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+)
+
+// ScreenShare is a screenshare we are sending, started with ZoomStreams.StartScreenShare
+type ScreenShare struct {
+	streams *ZoomStreams
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+
+	paused            int32
+	keyFrameRequested int32
+}
+
+/*
+StartScreenShare shares the H.264 frames of the source as our screen, use h264.NewAnnexBReader for .h264 files.
+Frames are sent at the given frame rate (DEFAULT_FRAME_RATE if 0), screen content rarely needs more than 5.
+
+It announces the screenshare with SetShareStatus and waits until zoom assigned us a sending ssrc and handed out the
+encryption key, the context only bounds that wait. Publishing then continues in the background until the source is
+drained or Stop is called. The resolution sent along with every packet is read from the SPS of the keyframes.
+*/
+func (streams *ZoomStreams) StartScreenShare(ctx context.Context, source h264.VideoSource, frameRate int) (*ScreenShare, error) {
+	if streams.streamType != rtp.STREAM_TYPE_SCREENSHARE {
+		return nil, ErrWrongStreamType
+	}
+	if frameRate <= 0 {
+		frameRate = DEFAULT_FRAME_RATE
+	}
+
+	session := streams.session
+	// wait for the ssrc and key of this share, not the ones of an earlier one
+	session.resetSharingState()
+	err := session.SetShareStatus(true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var ssrc int
+	var encryptKey string
+	err = session.waitForPublishState(ctx, func() bool {
+		ssrc = session.sendingSsrcs[rtp.STREAM_TYPE_SCREENSHARE]
+		encryptKey = session.sharingEncryptKey
+		return ssrc != 0 && encryptKey != ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	sharedMeetingKey, err := ZoomEscapedBase64Decode(encryptKey)
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := streams.newEncoder(ssrc, sharedMeetingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	shareCtx, cancel := context.WithCancel(context.Background())
+	share := &ScreenShare{
+		streams: streams,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		defer close(share.done)
+		share.err = streams.publishFrames(shareCtx, encoder, source, frameRate, &share.keyFrameRequested, &share.paused)
+		if share.err == context.Canceled {
+			share.err = nil
+		}
+	}()
+	return share, nil
+}
+
+// Pause freezes the screenshare for everyone, frames read from the source in the meantime are dropped
+func (share *ScreenShare) Pause() error {
+	session := share.streams.session
+	err := session.SendMessage(WS_SHARING_PAUSE_REQ, SharingPauseRequest{ID: session.JoinInfo.UserID})
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&share.paused, 1)
+	return nil
+}
+
+// Resume continues a paused screenshare from the next keyframe of the source, everything in between was dropped
+func (share *ScreenShare) Resume() error {
+	session := share.streams.session
+	err := session.SendMessage(WS_SHARING_RESUME_REQ, SharingResumeRequest{ID: session.JoinInfo.UserID})
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&share.keyFrameRequested, 1)
+	atomic.StoreInt32(&share.paused, 0)
+	return nil
+}
+
+// Wait blocks until the source is drained or Stop is called and returns why publishing failed, if it did
+func (share *ScreenShare) Wait() error {
+	<-share.done
+	return share.err
+}
+
+// Stop stops sending frames and tells everyone we are no longer sharing
+func (share *ScreenShare) Stop() error {
+	share.cancel()
+	publishErr := share.Wait()
+	share.streams.session.resetSharingState()
+	err := share.streams.session.SetShareStatus(false, false)
+	if publishErr != nil {
+		return publishErr
+	}
+	return err
+}
@@ -0,0 +1,227 @@
+package zoom
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp/ext"
+	"github.com/gorilla/websocket"
+	pionrtp "github.com/pion/rtp"
+)
+
+// endlessSource sends a keyframe followed by 9 slices over and over
+type endlessSource struct {
+	keyFrame []byte
+	frames   int
+}
+
+func (source *endlessSource) ReadFrame() ([]byte, error) {
+	source.frames++
+	if source.frames%10 == 1 {
+		return source.keyFrame, nil
+	}
+	return []byte{0, 0, 0, 1, 0x41, 0x9a, 0x02}, nil
+}
+
+func TestStartScreenShare(t *testing.T) {
+	ssrc := 16778243
+	encryptKey := base64.RawStdEncoding.EncodeToString(bytes.Repeat([]byte{0x11}, 32))
+	media := make(chan []byte, 1000)
+	requests := make(chan int, 10)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer connection.Close()
+
+		if r.URL.Path == "/media" {
+			for {
+				_, p, err := connection.ReadMessage()
+				if err != nil {
+					return
+				}
+				media <- p
+			}
+		}
+		for {
+			request := &GenericZoomMessage{}
+			err := connection.ReadJSON(request)
+			if err != nil {
+				return
+			}
+			requests <- request.Evt
+			if request.Evt == WS_CONF_SET_SHARE_STATUS_REQ {
+				status := &ConferenceSetShareStatusRequest{}
+				json.Unmarshal(request.Body, status)
+				if status.BOn {
+					connection.WriteJSON(&GenericZoomMessage{Evt: WS_SHARING_ENCRYPT_KEY_INDICATION, Body: []byte(`{"encryptKey":"` + encryptKey + `"}`)})
+					connection.WriteJSON(&GenericZoomMessage{Evt: WS_SHARING_ASSIGNED_SENDING_SSRC, Body: []byte(`{"ssrc":16778243}`)})
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	connection, _, err := websocket.DefaultDialer.Dial(url+"/session", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer connection.Close()
+	send, _, err := websocket.DefaultDialer.Dial(url+"/media", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer send.Close()
+
+	session := &ZoomSession{
+		JoinInfo:            &JoinConferenceResponse{UserID: 16778240, ZoomID: "ESIzRFVmd4iZqrvM3e7_AA"},
+		events:              newEventBus(),
+		requests:            newPendingRequests(),
+		websocketConnection: connection,
+	}
+	session.On(WS_SHARING_ASSIGNED_SENDING_SSRC, session.updateSharingSsrc)
+	session.On(WS_SHARING_ENCRYPT_KEY_INDICATION, session.updateSharingEncryptKey)
+	go session.readLoop(connection, func() {})
+
+	streams := &ZoomStreams{
+		send:       send,
+		decoder:    rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_SCREENSHARE),
+		session:    session,
+		streamType: rtp.STREAM_TYPE_SCREENSHARE,
+	}
+
+	// baseline 640x360, see TestParseSPS
+	sps := []byte{0, 0, 0, 1, 0x67, 0x42, 0xc0, 0x1e, 0xda, 0x02, 0x80, 0xbf, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04, 0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x58, 0xba, 0x80}
+	keyFrame := append(sps, 0, 0, 0, 1, 0x65, 0x88, 0x84)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	share, err := streams.StartScreenShare(ctx, &endlessSource{keyFrame: keyFrame}, 50)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// the screenshares of others are still decrypted with the meeting key
+	if _, err := streams.decoder.ParticipantRoster.GetSharedMeetingKey(); err == nil {
+		t.Error("the sharing encryption key replaced the key of the decoder")
+	}
+
+	nextPacket := func() *pionrtp.Packet {
+		select {
+		case p := <-media:
+			if p[0] != RTP_SCREENSHARE_PKT {
+				t.Errorf("expected a screenshare packet, got %x", p[0])
+				return nil
+			}
+			packet := &pionrtp.Packet{}
+			err := packet.Unmarshal(p[SCREENSHARE_PKT_HEADER_LENGTH:])
+			if err != nil {
+				t.Error(err)
+				return nil
+			}
+			return packet
+		case <-time.After(5 * time.Second):
+			t.Error("expected another frame")
+			return nil
+		}
+	}
+	isKeyFrame := func(packet *pionrtp.Packet) bool {
+		frameInfo := &ext.RtpExtFrameInfo{}
+		err := frameInfo.Unmarshal(packet.GetExtension(ext.RTP_EXTENSION_ID_SCREENSHARE_FRAME_INFO))
+		if err != nil {
+			t.Error(err)
+		}
+		return frameInfo.Independent
+	}
+
+	packet := nextPacket()
+	if packet == nil {
+		return
+	}
+	if packet.SSRC != uint32(ssrc) {
+		t.Errorf("expected ssrc %v, got %v", ssrc, packet.SSRC)
+	}
+	if !isKeyFrame(packet) {
+		t.Error("expected to start with a keyframe")
+	}
+	resolution := &ext.RtpExtResolution{}
+	err = resolution.Unmarshal(packet.GetExtension(ext.RTP_EXTENSION_ID_SCREENSHARE_RESOLUTION))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if resolution.Width != 640 || resolution.Height != 360 {
+		t.Errorf("expected 640x360, got %vx%v", resolution.Width, resolution.Height)
+	}
+
+	err = share.Pause()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// a frame could have been on its way while pausing
+	time.Sleep(100 * time.Millisecond)
+	for len(media) > 0 {
+		<-media
+	}
+	time.Sleep(100 * time.Millisecond)
+	if len(media) != 0 {
+		t.Errorf("expected nothing to be sent while paused, got %v packets", len(media))
+	}
+
+	err = share.Resume()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	packet = nextPacket()
+	if packet == nil {
+		return
+	}
+	if !isKeyFrame(packet) {
+		t.Error("expected to resume with the next keyframe of the source")
+	}
+
+	err = share.Stop()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if session.getSendingSsrc(rtp.STREAM_TYPE_SCREENSHARE) != 0 || session.sharingEncryptKey != "" {
+		t.Error("expected the ssrc and key of the share to be forgotten")
+	}
+
+	expected := []int{WS_CONF_SET_SHARE_STATUS_REQ, WS_SHARING_PAUSE_REQ, WS_SHARING_RESUME_REQ, WS_CONF_SET_SHARE_STATUS_REQ}
+	for _, evt := range expected {
+		select {
+		case received := <-requests:
+			if received != evt {
+				t.Errorf("expected %v, got %v", MessageNumberToName[evt], MessageNumberToName[received])
+			}
+		case <-time.After(5 * time.Second):
+			t.Errorf("expected %v", MessageNumberToName[evt])
+			return
+		}
+	}
+}
+
+func TestStartScreenShareWrongStreams(t *testing.T) {
+	streams := &ZoomStreams{streamType: rtp.STREAM_TYPE_VIDEO}
+	_, err := streams.StartScreenShare(context.Background(), &frameSource{}, 0)
+	if err != ErrWrongStreamType {
+		t.Errorf("expected ErrWrongStreamType, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package zoom
 
 import (
+	"context"
 	"crypto/tls"
 	"errors"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -31,12 +33,34 @@ type ZoomSession struct {
 	ZoomApiKey      string
 	ZoomApiSecret   string
 	JoinInfo        *JoinConferenceResponse
+	Roster          *Roster
 	ProxyURL        *url.URL
+	// set to nil to give up as soon as the connection drops
+	ReconnectPolicy *ReconnectPolicy
 
 	meetingOpt          string
 	httpClient          *http.Client
 	websocketConnection *websocket.Conn
 	sendSequenceNumber  uint32
+	events              *eventBus
+	requests            *pendingRequests
+	switches            chan *meetingSwitch
+	streams             []*ZoomStreams
+
+	// the ssrcs and keys zoom hands out for publishing, publishStateChanged is closed and replaced whenever one arrives
+	sendingSsrcs        map[rtp.StreamType]int
+	sharingEncryptKey   string
+	publishStateChanged chan struct{}
+
+	// breakout rooms, see JoinBreakoutRoom
+	breakoutRoom   string
+	mainMeetingOpt string
+
+	// lifecycle, see Connect
+	started bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
 
 	// RWG
 	RwgInfo   *RwgInfo
@@ -51,6 +75,7 @@ func NewZoomSession(meetingNumber string, meetingPassword string, username strin
 	if err != nil {
 		return nil, err
 	}
+	reconnectPolicy := DefaultReconnectPolicy
 	session := ZoomSession{
 		MeetingNumber:   strings.Replace(meetingNumber, " ", "", -1), // remove all
 		MeetingPassword: meetingPassword,
@@ -59,6 +84,12 @@ func NewZoomSession(meetingNumber string, meetingPassword string, username strin
 		ZoomApiType:     zoomApiType,
 		ZoomApiKey:      zoomApiKey,
 		ZoomApiSecret:   zoomApiSecret,
+		ReconnectPolicy: &reconnectPolicy,
+		Roster:          NewRoster(),
+		events:          newEventBus(),
+		requests:        newPendingRequests(),
+		switches:        make(chan *meetingSwitch),
+		done:            make(chan struct{}),
 	}
 
 	session.httpClient = &http.Client{
@@ -84,5 +115,31 @@ func NewZoomSession(meetingNumber string, meetingPassword string, username strin
 	}
 	session.httpClient.Transport = transport
 
+	// keep the roster up to date before any user defined handler runs
+	_, err = session.On(WS_CONF_ROSTER_INDICATION, session.updateRoster)
+	if err != nil {
+		return nil, err
+	}
+	_, err = session.On(WS_AUDIO_SSRC_INDICATION, session.updateAudioSsrc)
+	if err != nil {
+		return nil, err
+	}
+	_, err = session.On(WS_VIDEO_SSRC_INDICATION, session.updateVideoSsrc)
+	if err != nil {
+		return nil, err
+	}
+	_, err = session.On(WS_SHARING_ASSIGNED_SENDING_SSRC, session.updateSharingSsrc)
+	if err != nil {
+		return nil, err
+	}
+	_, err = session.On(WS_SHARING_ENCRYPT_KEY_INDICATION, session.updateSharingEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+	_, err = session.On(WS_CONF_COHOST_CHANGE_INDICATION, session.updateCoHost)
+	if err != nil {
+		return nil, err
+	}
+
 	return &session, nil
 }
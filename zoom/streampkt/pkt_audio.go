@@ -14,13 +14,17 @@ type ZoomAudioPkt struct {
 }
 
 func (pkt *ZoomAudioPkt) Unmarshal(data []byte) error {
-	// TODO check data not nil
-	// TODO: check min header length
+	if len(data) < 23 {
+		return errors.New("ZoomAudioPkt is too short")
+	}
 	if data[0] != 0x6B {
 		return errors.New("ZoomAudioPkt expects 0x6B as starting byte")
 	}
 
 	rtpPktSize := binary.BigEndian.Uint16(data[21:23])
+	if len(data) < int(23+rtpPktSize) {
+		return errors.New("ZoomAudioPkt is shorter than its rtp packet")
+	}
 	rtpPkt := data[23 : 23+rtpPktSize]
 
 	pkt.lenRtp = rtpPktSize
@@ -32,6 +36,14 @@ func (pkt *ZoomAudioPkt) Unmarshal(data []byte) error {
 	return nil
 }
 
+// Marshal wraps the rtp packet for sending, what the 20 bytes in front of the length mean is unknown so they stay empty (untested)
+func (pkt *ZoomAudioPkt) Marshal() []byte {
+	data := make([]byte, 23, 23+len(pkt.Rtp))
+	data[0] = 0x6B
+	binary.BigEndian.PutUint16(data[21:23], uint16(len(pkt.Rtp)))
+	return append(data, pkt.Rtp...)
+}
+
 func (pkt *ZoomAudioPkt) String() string {
 	return fmt.Sprintf("[ZoomAudioPkt lenRtp=%v rtp=%v additionalData=%v]", pkt.lenRtp, hex.EncodeToString(pkt.Rtp), hex.EncodeToString(pkt.AdditionalData))
 }
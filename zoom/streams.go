@@ -3,14 +3,17 @@ package zoom
 import (
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"sync"
 	"time"
 
 	"net/http"
 	"net/url"
 
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/h264"
 	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/opus"
 	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
 	"github.com/RealKeyboardWarrior/zoomer/zoom/streampkt"
@@ -25,13 +28,32 @@ const (
 	RTP_VIDEO_PKT    = 0x67
 	RTCP             = 0x4E
 	AES_GCM_IV_VALUE = 0x42
+
+	KEY_FRAME_REQUEST_INTERVAL = time.Second
 )
 
 type ZoomStreams struct {
+	mu   sync.Mutex
 	recv *websocket.Conn
 	send *websocket.Conn
+	// websocket connections only allow one writer at a time
+	sendMu sync.Mutex
 
 	decoder *rtp.ZoomRtpDecoder
+	// survives reconnects, unlike the decoder
+	maxTemporalID uint8
+	// when we last asked for a keyframe per ssrc, see requestKeyFrame
+	keyFrameRequests map[uint32]time.Time
+
+	session    *ZoomSession
+	subType    string
+	recvMode   string
+	streamType rtp.StreamType
+	closed     bool
+
+	sink MediaSink
+	// the default sink is ours to close, sinks passed in are not
+	ownsSink bool
 }
 
 func createWebSocketUrl(session *ZoomSession, subType string, mode string) string {
@@ -48,118 +70,178 @@ func createWebSocketUrl(session *ZoomSession, subType string, mode string) strin
 	return url.String()
 }
 
-func CreateZoomAudioStreams(session *ZoomSession) (*ZoomStreams, error) {
-	if session.JoinInfo == nil {
-		return nil, errors.New("Zoom session does not have valid JoinInfo")
-	}
+// everything received is passed to the sink, see MediaSink. a nil sink records to files (see FileSink)
+func CreateZoomAudioStreams(session *ZoomSession, sink MediaSink) (*ZoomStreams, error) {
+	return createZoomStreams(session, "a", "5", rtp.STREAM_TYPE_AUDIO, sink)
+}
 
-	if session.RwgInfo == nil {
-		return nil, errors.New("Zoom session does not have valid RwgInfo")
-	}
+func CreateZoomVideoStreams(session *ZoomSession, sink MediaSink) (*ZoomStreams, error) {
+	// Normal video camera sharing
+	return createZoomStreams(session, "v", "5", rtp.STREAM_TYPE_VIDEO, sink)
+}
 
-	if session.JoinInfo.ZoomID == "" {
-		return nil, errors.New("Zoom session does not have valid ZoomID")
-	}
+func CreateZoomScreenShareStreams(session *ZoomSession, sink MediaSink) (*ZoomStreams, error) {
+	return createZoomStreams(session, "s", "1", rtp.STREAM_TYPE_SCREENSHARE, sink)
+}
 
-	// Normal video camera sharing
-	downstream := createWebSocketUrl(session, "a", "5")
-	recv, err := createWebsocket("recv", downstream)
-	if err != nil {
-		return nil, err
+func createZoomStreams(session *ZoomSession, subType string, recvMode string, streamType rtp.StreamType, sink MediaSink) (*ZoomStreams, error) {
+	final := &ZoomStreams{
+		session:    session,
+		subType:    subType,
+		recvMode:   recvMode,
+		streamType: streamType,
+		sink:       sink,
+
+		maxTemporalID: rtp.MAX_TEMPORAL_ID,
+	}
+	if sink == nil {
+		final.sink = NewFileSink()
+		final.ownsSink = true
 	}
 
-	// Upstream for both screenshare and audio is 2.
-	// TODO: should be for video as well - verify
-	upstream := createWebSocketUrl(session, "a", "2")
-	send, err := createWebsocket("send", upstream)
+	err := final.connect()
 	if err != nil {
 		return nil, err
 	}
 
-	final := &ZoomStreams{
-		recv:    recv,
-		send:    send,
-		decoder: rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_AUDIO),
+	// the session moves the streams along when it switches to another meeting (breakout rooms)
+	session.addStreams(final)
+
+	return final, nil
+}
+
+// connect dials the media websockets for the meeting the session is currently in
+func (streams *ZoomStreams) connect() error {
+	recv, send, err := streams.dial()
+	if err != nil {
+		return err
 	}
+	streams.mu.Lock()
+	streams.install(recv, send)
+	streams.mu.Unlock()
 
-	go final.StartReceiveChannel()
+	go streams.receive(recv)
 
-	return final, nil
+	return nil
 }
 
-func CreateZoomVideoStreams(session *ZoomSession) (*ZoomStreams, error) {
+func (streams *ZoomStreams) dial() (*websocket.Conn, *websocket.Conn, error) {
+	session := streams.session
 	if session.JoinInfo == nil {
-		return nil, errors.New("Zoom session does not have valid JoinInfo")
+		return nil, nil, errors.New("Zoom session does not have valid JoinInfo")
 	}
 
 	if session.RwgInfo == nil {
-		return nil, errors.New("Zoom session does not have valid RwgInfo")
+		return nil, nil, errors.New("Zoom session does not have valid RwgInfo")
 	}
 
 	if session.JoinInfo.ZoomID == "" {
-		return nil, errors.New("Zoom session does not have valid ZoomID")
+		return nil, nil, errors.New("Zoom session does not have valid ZoomID")
 	}
 
-	// Normal video camera sharing
-	downstream := createWebSocketUrl(session, "v", "5")
+	downstream := createWebSocketUrl(session, streams.subType, streams.recvMode)
 	recv, err := createWebsocket("recv", downstream)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Upstream for both screenshare and audio is 2.
 	// TODO: should be for video as well - verify
-	upstream := createWebSocketUrl(session, "v", "2")
+	upstream := createWebSocketUrl(session, streams.subType, "2")
 	send, err := createWebsocket("send", upstream)
 	if err != nil {
-		return nil, err
+		recv.Close()
+		return nil, nil, err
 	}
+	return recv, send, nil
+}
 
-	final := &ZoomStreams{
-		recv:    recv,
-		send:    send,
-		decoder: rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_VIDEO),
+// install starts using the connections, it is called with the streams locked
+func (streams *ZoomStreams) install(recv *websocket.Conn, send *websocket.Conn) {
+	streams.recv = recv
+	streams.send = send
+	// keys and ssrcs are different in every meeting, so start with a clean decoder
+	streams.decoder = rtp.NewZoomRtpDecoder(streams.streamType)
+	streams.decoder.SetMaxTemporalID(streams.maxTemporalID)
+	// we don't know how to ask for a keyframe of a screenshare, so those are never held back after a loss
+	if streams.streamType == rtp.STREAM_TYPE_VIDEO {
+		streams.decoder.OnKeyFrameNeeded = streams.requestKeyFrame
 	}
-
-	go final.StartReceiveChannel()
-
-	return final, nil
 }
 
-func CreateZoomScreenShareStreams(session *ZoomSession) (*ZoomStreams, error) {
-	if session.JoinInfo == nil {
-		return nil, errors.New("Zoom session does not have valid JoinInfo")
+// Close closes the media websockets, the streams can not be used afterwards
+func (streams *ZoomStreams) Close() error {
+	err := streams.closeConnections()
+	if closer, ok := streams.sink.(io.Closer); ok && streams.ownsSink {
+		sinkErr := closer.Close()
+		if err == nil {
+			err = sinkErr
+		}
 	}
+	return err
+}
 
-	if session.RwgInfo == nil {
-		return nil, errors.New("Zoom session does not have valid RwgInfo")
-	}
+func (streams *ZoomStreams) closeConnections() error {
+	streams.mu.Lock()
+	defer streams.mu.Unlock()
 
-	if session.JoinInfo.ZoomID == "" {
-		return nil, errors.New("Zoom session does not have valid ZoomID")
+	streams.closed = true
+	recvErr := streams.recv.Close()
+	sendErr := streams.send.Close()
+	if recvErr != nil {
+		return recvErr
 	}
+	return sendErr
+}
 
-	downstream := createWebSocketUrl(session, "s", "1")
-	recv, err := createWebsocket("recv", downstream)
+func (streams *ZoomStreams) getDecoder() *rtp.ZoomRtpDecoder {
+	streams.mu.Lock()
+	defer streams.mu.Unlock()
+	return streams.decoder
+}
+
+// isClosed returns whether we closed the connection ourselves, with Close or by moving to another meeting
+func (streams *ZoomStreams) isClosed(connection *websocket.Conn) bool {
+	streams.mu.Lock()
+	defer streams.mu.Unlock()
+	return streams.closed || streams.recv != connection
+}
+
+/*
+switchMeeting re-dials the media websockets after the session moved to another meeting. The new connections
+replace the old ones under the lock, so streams closed by the user in the meantime stay closed.
+*/
+func (streams *ZoomStreams) switchMeeting() error {
+	recv, send, err := streams.dial()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	upstream := createWebSocketUrl(session, "s", "2")
-	send, err := createWebsocket("send", upstream)
-	if err != nil {
-		return nil, err
+	streams.mu.Lock()
+	if streams.closed {
+		// closed by the user, leave them alone
+		streams.mu.Unlock()
+		recv.Close()
+		send.Close()
+		return nil
 	}
+	oldRecv, oldSend := streams.recv, streams.send
+	streams.install(recv, send)
+	streams.mu.Unlock()
 
-	final := &ZoomStreams{
-		recv:    recv,
-		send:    send,
-		decoder: rtp.NewZoomRtpDecoder(rtp.STREAM_TYPE_SCREENSHARE),
+	// the receiver of the old connection sees it is no longer current and stops quietly
+	err = oldRecv.Close()
+	if err != nil {
+		log.Printf("Closing streams failed: %+v", err)
 	}
+	// don't cut off a write that is still going on
+	streams.sendMu.Lock()
+	oldSend.Close()
+	streams.sendMu.Unlock()
 
-	go final.StartReceiveChannel()
+	go streams.receive(recv)
 
-	return final, nil
+	return nil
 }
 
 func Recorder() (io.WriteCloser, error) {
@@ -196,7 +278,18 @@ func createWebsocket(name string, websocketUrl string) (*websocket.Conn, error)
 }
 
 func (streams *ZoomStreams) StartReceiveChannel() {
+	streams.mu.Lock()
 	connection := streams.recv
+	streams.mu.Unlock()
+
+	streams.receive(connection)
+}
+
+// receive reads from the connection until it fails or is closed by us
+func (streams *ZoomStreams) receive(connection *websocket.Conn) {
+	streams.mu.Lock()
+	decoder := streams.decoder
+	streams.mu.Unlock()
 
 	closeHandler := func(i int, msg string) error {
 		log.Printf("Closing : %v %v", i, msg)
@@ -204,84 +297,79 @@ func (streams *ZoomStreams) StartReceiveChannel() {
 	}
 	connection.SetCloseHandler(closeHandler)
 
-	recorder, err := Recorder()
-	if err != nil {
-		log.Fatal(err)
+	// a new opus decoder for every connection, the state from the previous meeting is of no use
+	var pcmDecoder *opus.PCMDecoder
+	if audioSink, ok := streams.sink.(opus.AudioSink); ok && streams.streamType == rtp.STREAM_TYPE_AUDIO {
+		pcmDecoder = opus.NewPCMDecoder(audioSink)
 	}
-	defer recorder.Close()
-
-	audioRecorder, err := opus.CreateNewPCMRecorder()
-	if err != nil {
-		log.Fatal(err)
-	}
-	go (func() {
-		time.Sleep(20 * time.Second)
-		audioRecorder.Close()
-	})()
-	//defer audioRecorder.Close()
-
-	decoder := streams.decoder
 
 	for {
 		messageType, p, err := connection.ReadMessage()
 		if err != nil {
-			log.Fatal(err)
+			// we closed the connection ourselves
+			if streams.isClosed(connection) {
+				return
+			}
+			streams.sink.OnError(err)
 			return
 		}
+		if len(p) == 0 {
+			continue
+		}
 
 		// Pong
 		if p[0] == PING {
 			err := connection.WriteMessage(websocket.BinaryMessage, p)
 			if err != nil {
-				log.Fatal(err)
-				return
+				streams.sink.OnError(err)
 			}
 			// RTP packet
 		} else if p[0] == RTP_AUDIO_PKT {
 			zoomPkt := &streampkt.ZoomAudioPkt{}
 			err := zoomPkt.Unmarshal(p)
 			if err != nil {
-				log.Fatal(err)
-				return
+				streams.sink.OnError(err)
+				continue
 			}
 			log.Printf("%v", zoomPkt)
 			sample, err := decoder.Decode(zoomPkt.Rtp)
 			if err != nil {
-				log.Fatal(err)
-				return
+				streams.sink.OnError(err)
+				continue
 			}
 
 			if sample != nil {
-				err = audioRecorder.Record(sample)
-				if err != nil {
-					log.Fatal(err)
-					return
+				streams.emitSample(sample, true)
+				if pcmDecoder != nil {
+					err = pcmDecoder.Decode(sample.UserID, sample.PacketTimestamp, sample.Data)
+					if err != nil {
+						streams.sink.OnError(fmt.Errorf("decoding audio of %v failed: %w", sample.UserID, err))
+					}
 				}
 			}
 		} else if p[0] == RTP_SCREENSHARE_PKT || p[0] == RTP_VIDEO_PKT {
 			log.Printf("pkt = %v", hex.EncodeToString(p))
-			start := 4
+			start := SCREENSHARE_PKT_HEADER_LENGTH
 			if p[0] == RTP_VIDEO_PKT {
-				start = 28
+				start = VIDEO_PKT_HEADER_LENGTH
+			}
+			if len(p) <= start {
+				streams.sink.OnError(fmt.Errorf("media packet too short: %v", hex.EncodeToString(p)))
+				continue
 			}
 			sample, err := decoder.Decode(p[start:])
 			if err != nil {
-				log.Fatal(err)
-				return
+				streams.sink.OnError(err)
+				continue
 			}
 			if sample != nil {
-				_, err = recorder.Write(sample.Data)
-				if err != nil {
-					log.Fatal(err)
-					return
-				}
+				streams.emitSample(sample, h264.IsKeyFrame(sample.Data))
 			}
 
 		} else if p[0] == RTCP {
 			_, err := rtp.RtcpProcess(p[4:])
 			if err != nil {
-				log.Fatal(err)
-				return
+				streams.sink.OnError(err)
 			}
 		} else if p[0] == AES_GCM_IV_VALUE {
 			// log.Printf("AES_GCM_IV_VALUE IV=%v", p[4:])
@@ -292,13 +380,51 @@ func (streams *ZoomStreams) StartReceiveChannel() {
 
 }
 
+// requestKeyFrame asks for a keyframe after we lost packets, at most every KEY_FRAME_REQUEST_INTERVAL per ssrc
+func (streams *ZoomStreams) requestKeyFrame(ssrc uint32) {
+	streams.mu.Lock()
+	if time.Since(streams.keyFrameRequests[ssrc]) < KEY_FRAME_REQUEST_INTERVAL {
+		streams.mu.Unlock()
+		return
+	}
+	if streams.keyFrameRequests == nil {
+		streams.keyFrameRequests = make(map[uint32]time.Time)
+	}
+	streams.keyFrameRequests[ssrc] = time.Now()
+	streams.mu.Unlock()
+
+	err := streams.session.RequestKeyFrame(int(ssrc))
+	if err != nil {
+		streams.sink.OnError(fmt.Errorf("requesting a keyframe from %v failed: %w", ssrc, err))
+	}
+}
+
+func (streams *ZoomStreams) emitSample(sample *rtp.Sample, keyFrame bool) {
+	streams.sink.OnSample(&MediaSample{
+		SSRC:       sample.SSRC,
+		UserID:     sample.UserID,
+		StreamType: streams.streamType,
+		Timestamp:  sample.PacketTimestamp,
+		KeyFrame:   keyFrame,
+		Data:       sample.Data,
+	})
+}
+
+// SetMaxTemporalID limits the video and screenshare we receive to the lower temporal layers, see ZoomRtpDecoder.SetMaxTemporalID
+func (streams *ZoomStreams) SetMaxTemporalID(maxTemporalID uint8) {
+	streams.mu.Lock()
+	defer streams.mu.Unlock()
+	streams.maxTemporalID = maxTemporalID
+	streams.decoder.SetMaxTemporalID(maxTemporalID)
+}
+
 func (streams *ZoomStreams) SetSharedMeetingKey(encryptionKey string) error {
 	sharedMeetingKey, err := ZoomEscapedBase64Decode(encryptionKey)
 	if err != nil {
 		return err
 	}
 
-	streams.decoder.ParticipantRoster.SetSharedMeetingKey(sharedMeetingKey)
+	streams.getDecoder().ParticipantRoster.SetSharedMeetingKey(sharedMeetingKey)
 	return nil
 }
 
@@ -308,10 +434,10 @@ func (streams *ZoomStreams) AddParticipant(userId int, zoomId string) error {
 		return err
 	}
 
-	streams.decoder.ParticipantRoster.AddParticipant(userId, secretNonce)
+	streams.getDecoder().ParticipantRoster.AddParticipant(userId, secretNonce)
 	return nil
 }
 
 func (streams *ZoomStreams) AddSsrcForParticipant(userId int, ssrc int) error {
-	return streams.AddSsrcForParticipant(userId, ssrc)
+	return streams.getDecoder().ParticipantRoster.AddSsrcForParticipant(userId, ssrc)
 }
@@ -0,0 +1,113 @@
+package zoom
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/codecs/h264"
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+)
+
+// VideoWriterFactory opens the writer for the video of a participant, segment counts up from 0 every time their SPS changes
+type VideoWriterFactory func(userID int, ssrc uint32, segment int) (io.WriteCloser, error)
+
+type videoTrack struct {
+	userID  int
+	segment int
+	sps     []byte
+	writer  io.WriteCloser
+}
+
+/*
+ParticipantVideoSink is a MediaSink that splits video and screenshare by SSRC, so every participant
+ends up in their own annex B H.264 stream.
+
+A new writer is opened whenever the SPS of a participant changes (for instance a different resolution),
+players don't cope with that in the middle of a stream. Streams only start at a keyframe, everything before
+the first one can't be decoded anyway.
+*/
+type ParticipantVideoSink struct {
+	newWriter VideoWriterFactory
+
+	mu     sync.Mutex
+	tracks map[ /*ssrc*/ uint32]*videoTrack
+}
+
+func NewParticipantVideoSink(newWriter VideoWriterFactory) *ParticipantVideoSink {
+	return &ParticipantVideoSink{
+		newWriter: newWriter,
+		tracks:    make(map[uint32]*videoTrack),
+	}
+}
+
+func (sink *ParticipantVideoSink) OnSample(sample *MediaSample) {
+	if sample.StreamType == rtp.STREAM_TYPE_AUDIO {
+		return
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	track := sink.tracks[sample.SSRC]
+	if track == nil {
+		track = &videoTrack{
+			userID:  sample.UserID,
+			segment: -1,
+		}
+		sink.tracks[sample.SSRC] = track
+	}
+
+	sps := h264.FindNalu(sample.Data, h264.NALU_TYPE_SPS)
+	if sps != nil && !bytes.Equal(sps, track.sps) {
+		if track.writer != nil {
+			err := track.writer.Close()
+			if err != nil {
+				log.Printf("Closing video of %v failed: %+v", track.userID, err)
+			}
+			track.writer = nil
+		}
+		track.sps = append([]byte{}, sps...)
+	}
+
+	if track.writer == nil {
+		// the SPS may come in its own sample just before the keyframe
+		if !sample.KeyFrame && sps == nil {
+			return
+		}
+		track.segment++
+		writer, err := sink.newWriter(track.userID, sample.SSRC, track.segment)
+		if err != nil {
+			log.Printf("Opening video of %v failed: %+v", track.userID, err)
+			return
+		}
+		track.writer = writer
+	}
+
+	_, err := track.writer.Write(sample.Data)
+	if err != nil {
+		log.Printf("Writing video of %v failed: %+v", track.userID, err)
+	}
+}
+
+func (sink *ParticipantVideoSink) OnError(err error) {
+	log.Printf("Media error: %+v", err)
+}
+
+func (sink *ParticipantVideoSink) Close() error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+
+	var firstErr error
+	for ssrc, track := range sink.tracks {
+		if track.writer != nil {
+			err := track.writer.Close()
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		delete(sink.tracks, ssrc)
+	}
+	return firstErr
+}
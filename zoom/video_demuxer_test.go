@@ -0,0 +1,68 @@
+package zoom
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/RealKeyboardWarrior/zoomer/zoom/rtp"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (buffer *bufferCloser) Close() error {
+	buffer.closed = true
+	return nil
+}
+
+func TestParticipantVideoSink(t *testing.T) {
+	writers := make(map[string]*bufferCloser)
+	sink := NewParticipantVideoSink(func(userID int, ssrc uint32, segment int) (io.WriteCloser, error) {
+		writer := &bufferCloser{}
+		writers[fmt.Sprintf("%d-%d", userID, segment)] = writer
+		return writer, nil
+	})
+
+	spsA := []byte{0, 0, 0, 1, 0x67, 0x01}
+	spsB := []byte{0, 0, 0, 1, 0x67, 0x02}
+	idr := []byte{0, 0, 0, 1, 0x65, 0xAA}
+	slice := []byte{0, 0, 0, 1, 0x41, 0xBB}
+
+	video := func(ssrc uint32, userID int, data ...[]byte) {
+		sample := bytes.Join(data, nil)
+		sink.OnSample(&MediaSample{
+			SSRC:       ssrc,
+			UserID:     userID,
+			StreamType: rtp.STREAM_TYPE_VIDEO,
+			KeyFrame:   bytes.Contains(sample, []byte{0, 0, 1, 0x65}),
+			Data:       sample,
+		})
+	}
+
+	// nothing to decode before the first keyframe
+	video(1, 100, slice)
+	video(1, 100, spsA, idr)
+	video(2, 200, spsA, idr)
+	video(1, 100, slice)
+	// resolution change for 100
+	video(1, 100, spsB, idr)
+
+	if len(writers) != 3 {
+		t.Errorf("expected 3 recordings, got %v", len(writers))
+		return
+	}
+	first := writers["100-0"]
+	if !first.closed || !bytes.Equal(first.Bytes(), bytes.Join([][]byte{spsA, idr, slice}, nil)) {
+		t.Errorf("unexpected first recording of 100: %x", first.Bytes())
+	}
+	if !bytes.Equal(writers["100-1"].Bytes(), bytes.Join([][]byte{spsB, idr}, nil)) {
+		t.Errorf("unexpected second recording of 100: %x", writers["100-1"].Bytes())
+	}
+	if !bytes.Equal(writers["200-0"].Bytes(), bytes.Join([][]byte{spsA, idr}, nil)) {
+		t.Errorf("unexpected recording of 200: %x", writers["200-0"].Bytes())
+	}
+}
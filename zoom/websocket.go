@@ -1,11 +1,11 @@
 package zoom
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
-	"os"
-	"os/signal"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -18,7 +18,7 @@ import (
 	"strconv"
 )
 
-func (session *ZoomSession) GetWebsocketUrl(meetingInfo *MeetingInfo, wasInWaitingRoom bool) (string, error) {
+func (session *ZoomSession) GetWebsocketUrl(meetingInfo *MeetingInfo, rejoin bool) (string, error) {
 	if len(meetingInfo.Result.EncryptedRWC) < 1 {
 		return "", errors.New("No RWC hosts found")
 	}
@@ -52,7 +52,8 @@ func (session *ZoomSession) GetWebsocketUrl(meetingInfo *MeetingInfo, wasInWaiti
 	values.Set("tk", "")
 	values.Set("cfs", "0")
 	// "opt" is a parameter to specify a meeting within a meeting, for instance breakout rooms or the main meeting in a meeting with waiting room enabled
-	if wasInWaitingRoom {
+	// zoomid and participantID make sure that we come back as the same participant, after the waiting room or when reconnecting
+	if rejoin {
 		values.Set("opt", session.meetingOpt)
 		values.Set("zoomid", session.JoinInfo.ZoomID)
 		values.Set("participantID", strconv.Itoa(session.JoinInfo.ParticipantID))
@@ -68,11 +69,18 @@ func (session *ZoomSession) GetWebsocketUrl(meetingInfo *MeetingInfo, wasInWaiti
 
 type onMessage func(session *ZoomSession, message Message) error
 
-func (session *ZoomSession) makeWebsocketConnection(onMessageFunction onMessage, wasInWaitingRoom bool) error {
+// zoom sends pings (aside from regular websocket ones) approximately every minute of the form "{"evt":0,"seq":74}"
+const keepaliveInterval = 60 * time.Second
+
+// how long we wait for the server to acknowledge our close message before dropping the connection
+const closeTimeout = 5 * time.Second
+
+// dialWebsocket fetches fresh tokens for the meeting and opens the signaling websocket
+func (session *ZoomSession) dialWebsocket(ctx context.Context, rejoin bool) (*websocket.Conn, error) {
 	// get the rwc token and other info needed to construct the websocket url for the meeting
 	meetingInfo, cookieString, err := session.GetMeetingInfoData()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	session.RwgCookie = cookieString
 	log.Printf("%v", meetingInfo)
@@ -80,13 +88,13 @@ func (session *ZoomSession) makeWebsocketConnection(onMessageFunction onMessage,
 	pingRwcServer := getRwgPingServer(meetingInfo)
 	rwgInfo, err := session.getRwgPingData(meetingInfo, pingRwcServer)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	session.RwgInfo = rwgInfo
 
-	websocketUrl, err := session.GetWebsocketUrl(meetingInfo, wasInWaitingRoom)
+	websocketUrl, err := session.GetWebsocketUrl(meetingInfo, rejoin)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	websocketHeaders := http.Header{}
@@ -102,129 +110,322 @@ func (session *ZoomSession) makeWebsocketConnection(onMessageFunction onMessage,
 		dialer.Proxy = http.ProxyURL(session.ProxyURL)
 	}
 	log.Printf("Dialing : %v", websocketUrl)
-	connection, _, err := dialer.Dial(websocketUrl, websocketHeaders)
+	connection, _, err := dialer.DialContext(ctx, websocketUrl, websocketHeaders)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	log.Printf("Dialed : %v", websocketUrl)
+
+	session.mu.Lock()
 	session.websocketConnection = connection
+	session.mu.Unlock()
+
+	return connection, nil
+}
 
-	defer connection.Close()
+/*
+meetingSwitch asks the session to move to another meeting within the meeting, for instance when we are
+admitted from the waiting room or join a breakout room. We have to reconnect with the "opt" of that meeting.
+*/
+type meetingSwitch struct {
+	opt string
+	// closed once we are in the new meeting, may be nil
+	joined chan struct{}
+}
 
-	done := make(chan struct{})
+/*
+readLoop handles the messages of a single websocket connection until it fails, it returns a switch when the
+connection has to be re-dialed because we were admitted from the waiting room.
 
-	var message *GenericZoomMessage
-	go func() {
-		defer close(done)
-		for {
-			// reset struct
-			message = &GenericZoomMessage{}
+When the websocket connection is established zoom sends a WS_CONF_JOIN_RES along with a bunch of other
+things, the join response is stored before any of the handlers run because it's necessary for sending
+chats etc. We only consider ourselves joined once the message after it did not put us on hold.
+*/
+func (session *ZoomSession) readLoop(connection *websocket.Conn, signalJoined func()) (*meetingSwitch, error) {
+	joinReceived := false
+	inWaitingRoom := false
+
+	for {
+		message := &GenericZoomMessage{}
+		err := connection.ReadJSON(message)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Received message (Evt: %s = %d; Seq: %d): %s", MessageNumberToName[message.Evt], message.Evt, message.Seq, string(message.Body))
+		session.requests.resolve(message)
 
-			err := connection.ReadJSON(&message)
+		switch message.Evt {
+		case WS_CONF_JOIN_RES:
+			bodyData := JoinConferenceResponse{}
+			err := json.Unmarshal(message.Body, &bodyData)
 			if err != nil {
-				log.Print("failed to read:", err)
-				return
+				return nil, err
 			}
-			log.Printf("Received message (Evt: %s = %d; Seq: %d): %s", MessageNumberToName[message.Evt], message.Evt, message.Seq, string(message.Body))
-
-			switch message.Evt {
-			/*
-				if we receive a WS_CONF_JOIN_RES message (this is sent along with a bunch of other things when the websocket connection is established) will also store some info from the join response that is necessary for sending chats into the session state
-				important that this is done before any other handling functions
-			*/
-			case WS_CONF_JOIN_RES:
-				bodyData := JoinConferenceResponse{}
-				err := json.Unmarshal(message.Body, &bodyData)
-				if err != nil {
-					log.Printf("Failed to unmarshal json: %v", err)
-					return
-				}
-				if session.JoinInfo != nil {
-					log.Fatal("JoinConferenceResponse received twice")
-					return
-				}
-				session.JoinInfo = &bodyData
-			/* figure out whether we are in the waiting room or not */
-			case WS_CONF_HOLD_CHANGE_INDICATION:
-				bodyData := ConferenceHoldChangeIndication{}
+			// we receive a new join response after being admitted from the waiting room
+			session.JoinInfo = &bodyData
+			joinReceived = true
+		/* figure out whether we are in the waiting room or not */
+		case WS_CONF_HOLD_CHANGE_INDICATION:
+			bodyData := ConferenceHoldChangeIndication{}
+			err := json.Unmarshal(message.Body, &bodyData)
+			if err != nil {
+				return nil, err
+			}
+			inWaitingRoom = bodyData.BHold
+		/* get the opt for the meeting we are admitted to, we have to reconnect with it */
+		case WS_CONF_OPTION_INDICATION:
+			if inWaitingRoom {
+				bodyData := ConferenceOptionIndication{}
 				err := json.Unmarshal(message.Body, &bodyData)
 				if err != nil {
-					log.Printf("Failed to unmarshal json: %v", err)
-					return
-				}
-				if bodyData.BHold == true {
-					wasInWaitingRoom = true
-				}
-			/* get the opt for the waiting room */
-			case WS_CONF_OPTION_INDICATION:
-				if wasInWaitingRoom {
-					bodyData := ConferenceOptionIndication{}
-					err := json.Unmarshal(message.Body, &bodyData)
-					if err != nil {
-						log.Printf("Failed to unmarshal json: %v", err)
-						return
-					}
-					session.meetingOpt = bodyData.Opt
+					return nil, err
 				}
+				return &meetingSwitch{opt: bodyData.Opt}, nil
+			}
+		case WS_CONF_END_INDICATION:
+			// breakout rooms end while the main meeting goes on, zoom expects us to go back to it
+			if mainMeetingOpt, ok := session.leaveBreakoutRoom(); ok {
+				joined := make(chan struct{})
+				go session.reconnectStreamsWhenJoined(joined)
+				return &meetingSwitch{opt: mainMeetingOpt, joined: joined}, nil
 			}
+			return nil, ErrMeetingEnded
+		}
 
-			// dont run the user defined functions in the waiting room
-			if !wasInWaitingRoom {
-				// convert generic json message to go type
-				m, err := GetMessageBody(message)
-				if err != nil {
-					// log.Printf("Decoding message failed: %+v", err)
-					continue
-				}
-				err = onMessageFunction(session, m)
-				if err != nil {
-					log.Printf("User defined function failed: %+v", err)
-				}
+		// dont run the user defined functions in the waiting room
+		if inWaitingRoom {
+			continue
+		}
+		if joinReceived && message.Evt != WS_CONF_JOIN_RES {
+			signalJoined()
+		}
+		// checked with every message so the handlers don't have to deal with events from another goroutine
+		if session.Roster != nil {
+			for _, event := range session.Roster.dropStale(time.Now()) {
+				session.emit(event.evt, event.message)
 			}
 		}
+
+		// convert generic json message to go type
+		m, err := GetMessageBody(message)
+		if err != nil {
+			// log.Printf("Decoding message failed: %+v", err)
+			continue
+		}
+		session.events.dispatch(session, message.Evt, m)
+	}
+}
+
+type readResult struct {
+	next *meetingSwitch
+	err  error
+}
+
+// serve keeps a single websocket connection alive until it fails, the session is closed or has to switch meetings
+func (session *ZoomSession) serve(ctx context.Context, connection *websocket.Conn, signalJoined func()) (*meetingSwitch, error) {
+	defer func() {
+		connection.Close()
+		session.mu.Lock()
+		if session.websocketConnection == connection {
+			session.websocketConnection = nil
+		}
+		session.mu.Unlock()
+	}()
+
+	readDone := make(chan readResult, 1)
+	go func() {
+		next, err := session.readLoop(connection, signalJoined)
+		readDone <- readResult{next: next, err: err}
 	}()
 
-	interrupt := make(chan os.Signal, 1)
-	signal.Notify(interrupt, os.Interrupt)
-	// zoom sends pings (aside from regular websocket ones) approximately every minute of the form "{"evt":0,"seq":74}"
-	minutelyJsonPingTicker := time.NewTicker(60 * time.Second)
-	defer minutelyJsonPingTicker.Stop()
+	keepaliveTicker := time.NewTicker(keepaliveInterval)
+	defer keepaliveTicker.Stop()
 
 	for {
-		if wasInWaitingRoom { // get out of the select loop if we are in the waiting room otherwise the function is gonna return and not give us an opportunity to connect to the breakout room
-			break
-		}
 		select {
-		case <-minutelyJsonPingTicker.C:
-			if wasInWaitingRoom { // get out of the select loop if we are in the waiting room otherwise the function is gonna return and not give us an opportunity to connect to the breakout room
-				break
-			}
-			session.SendMessage(connection, WS_CONN_KEEPALIVE, nil)
-			break
-		case <-done:
-			if wasInWaitingRoom { // get out of the select loop if we are in the waiting room otherwise the function is gonna return and not give us an opportunity to connect to the breakout room
-				break
-			}
-			return nil
-		case <-interrupt:
+		case <-keepaliveTicker.C:
+			session.SendMessage(WS_CONN_KEEPALIVE, nil)
+		case result := <-readDone:
+			return result.next, result.err
+		case next := <-session.switches:
+			// we don't leave the meeting, zoom moves us over as soon as we connect with the new opt
+			return next, nil
+		case <-ctx.Done():
 			// Cleanly close the connection by sending a close message and then
 			// waiting (with timeout) for the server to close the connection.
-			err := connection.WriteMessage(websocket.CloseMessage, []byte(""))
-			if err != nil {
-				return err
+			session.mu.Lock()
+			err := connection.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			session.mu.Unlock()
+			if err == nil {
+				select {
+				case <-readDone:
+				case <-time.After(closeTimeout):
+				}
 			}
-			<-done
-			return nil
+			return nil, nil
 		}
 	}
+}
 
-	if wasInWaitingRoom {
-		return session.makeWebsocketConnection(onMessageFunction, true)
+func signalOnce(joined chan struct{}) func() {
+	var joinedOnce sync.Once
+	return func() {
+		joinedOnce.Do(func() {
+			close(joined)
+		})
 	}
+}
+
+// run serves connections until the session is closed or fails, re-dialing whenever we switch meetings or lose the connection
+func (session *ZoomSession) run(ctx context.Context, connection *websocket.Conn, joined chan struct{}) {
+	signalJoined := signalOnce(joined)
+
+	for {
+		next, err := session.serve(ctx, connection, signalJoined)
+		if ctx.Err() != nil {
+			// closed by us
+			session.terminate(nil)
+			return
+		}
+
+		if next != nil {
+			// "opt" is the meeting within the meeting we are moving to
+			session.mu.Lock()
+			session.meetingOpt = next.opt
+			session.mu.Unlock()
+			// the new meeting sends us its full roster
+			session.Roster.reset()
+			// and assigns new ssrcs to whatever we send
+			session.resetPublishState()
+			if next.joined != nil {
+				signalJoined = signalOnce(next.joined)
+			}
+			connection, err = session.dialWebsocket(ctx, true)
+			if err == nil {
+				continue
+			}
+		}
+
+		if !session.shouldReconnect(err) {
+			session.terminate(err)
+			return
+		}
+		connection, err = session.reconnect(ctx, err)
+		if connection == nil {
+			session.terminate(err)
+			return
+		}
+		session.Roster.markStale()
+	}
+}
+
+func (session *ZoomSession) terminate(err error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.websocketConnection = nil
+	session.err = err
+	close(session.done)
+
+	for _, streams := range session.streams {
+		streams.Close()
+	}
+}
+
+/*
+Connect joins the meeting and returns as soon as we are in it, messages are handled in the background
+until the session is closed with Leave/Close or the connection fails, see Done.
+
+The context is only used for joining, cancelling it afterwards does not affect the session.
+*/
+func (session *ZoomSession) Connect(ctx context.Context) error {
+	session.mu.Lock()
+	if session.started {
+		session.mu.Unlock()
+		return ErrAlreadyConnected
+	}
+	session.started = true
+	runCtx, cancel := context.WithCancel(context.Background())
+	session.cancel = cancel
+	session.mu.Unlock()
+
+	connection, err := session.dialWebsocket(ctx, false)
+	if err != nil {
+		cancel()
+		session.terminate(err)
+		return err
+	}
+
+	joined := make(chan struct{})
+	go session.run(runCtx, connection, joined)
+
+	select {
+	case <-joined:
+		return nil
+	case <-session.done:
+		return session.Err()
+	case <-ctx.Done():
+		session.Close()
+		return ctx.Err()
+	}
+}
+
+// Leave tells zoom we are leaving the meeting and waits for the connection to be torn down
+func (session *ZoomSession) Leave() error {
+	session.mu.Lock()
+	if !session.started {
+		session.mu.Unlock()
+		return ErrNotConnected
+	}
+	cancel := session.cancel
+	session.mu.Unlock()
 
-	return nil
+	// nothing to tell zoom while we are reconnecting
+	err := session.SendMessage(WS_CONF_LEAVE_REQ, ConferenceLeaveRequest{})
+	if err == ErrNotConnected {
+		err = nil
+	}
+	cancel()
+	<-session.done
+	return err
 }
 
+// Close is the same as Leave but does not complain about sessions that were never connected
+func (session *ZoomSession) Close() error {
+	err := session.Leave()
+	if err == ErrNotConnected {
+		return nil
+	}
+	return err
+}
+
+// Done returns a channel that receives the reason the session ended, nil if it was closed by us
+func (session *ZoomSession) Done() <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		<-session.done
+		result <- session.Err()
+		close(result)
+	}()
+	return result
+}
+
+// Err returns the reason the session ended, nil while it is still running or if it was closed by us
+func (session *ZoomSession) Err() error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.err
+}
+
+// MakeWebsocketConnection joins the meeting and blocks until the session ends
+// onMessageFunction may be nil when all handlers are registered through session.On
 func (session *ZoomSession) MakeWebsocketConnection(onMessageFunction onMessage) error {
-	return session.makeWebsocketConnection(onMessageFunction, false)
+	if onMessageFunction != nil {
+		session.OnMessage(onMessageFunction)
+	}
+	err := session.Connect(context.Background())
+	if err != nil {
+		return err
+	}
+	return <-session.Done()
 }